@@ -24,6 +24,22 @@ func main() {
 		cmd.HandleInit(os.Args[2:])
 		return
 	}
+	if os.Args[1] == "presets" {
+		cmd.HandlePresets(os.Args[2:])
+		return
+	}
+	if os.Args[1] == "detect" {
+		cmd.HandleDetect(os.Args[2:])
+		return
+	}
+	if os.Args[1] == "config" {
+		cmd.HandleConfig(os.Args[2:])
+		return
+	}
+	if os.Args[1] == "migrate" {
+		cmd.HandleMigrate(os.Args[2:])
+		return
+	}
 
 	// For other commands, load manager
 	mgr, err := manager.New(cwd)
@@ -32,35 +48,48 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --env / --env-file apply to every executing command
+	args, err := cmd.ParseEnvFlags(mgr, os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	switch os.Args[1] {
 	case "run":
-		cmd.HandleRun(mgr, os.Args[2:])
+		cmd.HandleRun(mgr, args)
 	case "build":
-		cmd.HandleBuild(mgr, os.Args[2:])
+		cmd.HandleBuild(mgr, args)
 	case "add":
-		cmd.HandleAdd(mgr, os.Args[2:])
+		cmd.HandleAdd(mgr, args)
 	case "sync":
-		cmd.HandleSync(mgr, os.Args[2:])
+		cmd.HandleSync(mgr, args)
 	case "up":
-		cmd.HandleUp(mgr, os.Args[2:])
+		cmd.HandleUp(mgr, args)
 	case "watch":
-		cmd.HandleWatch(mgr, os.Args[2:])
+		cmd.HandleWatch(mgr, args)
 	case "lfs":
-		cmd.HandleLfs(mgr, os.Args[2:])
+		cmd.HandleLfs(mgr, args)
 	case "install-self":
 		cmd.HandleInstallSelf()
 	case "remove":
-		cmd.HandleRemove(mgr, os.Args[2:])
+		cmd.HandleRemove(mgr, args)
 	case "ls":
 		cmd.HandleLs(mgr)
 	case "lsproj":
 		cmd.HandleLsproj()
 	case "query":
-		cmd.HandleQuery(mgr, os.Args[2:])
+		cmd.HandleQuery(mgr, args)
+	case "scripts":
+		cmd.HandleScripts(mgr, args)
+	case "secrets":
+		cmd.HandleSecrets(mgr, args)
 	case "info":
 		cmd.HandleInfo(mgr)
+	case "doctor":
+		cmd.HandleDoctor(mgr, args)
 	default:
 		// Attempt to handle as a generic script command
-		cmd.HandleGenericScript(mgr, os.Args[1], os.Args[2:])
+		cmd.HandleGenericScript(mgr, os.Args[1], args)
 	}
 }
\ No newline at end of file
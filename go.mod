@@ -2,4 +2,10 @@ module mngproj
 
 go 1.25
 
-require github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+require (
+	github.com/pelletier/go-toml/v2 v2.2.4
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -1,17 +1,21 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"mngproj/pkg/config"
 	"mngproj/pkg/manager"
-	"mngproj/pkg/utils"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"text/tabwriter"
 )
 
@@ -19,9 +23,9 @@ func PrintUsage() {
 	fmt.Println("mngproj - Monorepo Polyglot Manager")
 	fmt.Println("\nUsage: mngproj <command> [arguments...]")
 	fmt.Println("\nCore Workflow:")
-	fmt.Println("  init [type]      Initialize a new project (e.g., mngproj init python)")
+	fmt.Println("  init [type]      Initialize a new project (e.g., mngproj init python); detects types when omitted")
 	fmt.Println("  add <comp> <pkg> Add a dependency to a component and sync (e.g., mngproj add api requests)")
-	fmt.Println("  sync [comp]      Sync dependencies/tools for components (generates manifest files)")
+	fmt.Println("  sync [comp]      Sync dependencies/tools for components (generates manifest files; -j N in parallel)")
 	fmt.Println("  run <comp>       Run a component's default run script")
 	fmt.Println("  build <comp>     Build a component")
 	fmt.Println("  up [comp/grp]    Run multiple components/groups in parallel with aggregated logs")
@@ -31,20 +35,110 @@ func PrintUsage() {
 	fmt.Println("  ls               List all components in the current project")
 	fmt.Println("  lsproj           List all mngproj projects in the current directory tree")
 	fmt.Println("  info             Show project information and paths")
+	fmt.Println("  doctor [comp]    Check required tools and env variables before starting components")
 	fmt.Println("  lfs [mb]         Scan for large files (>100MB default) and add to .gitattributes")
 	fmt.Println("  install-self     Build and install mngproj to the system (go install)")
 	fmt.Println("  remove <comp>    Remove a package from a component")
 	fmt.Println("  query            Output component configuration as JSON")
+	fmt.Println("  scripts [comp]   List a component's scripts with their description and source preset (project scripts without comp)")
+	fmt.Println("  secrets <cmd>    Manage encrypted secrets: set|get|rm <comp> KEY [VALUE], ls [comp]")
+	fmt.Println("  detect [dir]     Propose components from files on disk (--write adds them to mngproj.toml)")
+	fmt.Println("  presets ls       List available presets and the layer providing them")
+	fmt.Println("  presets eject    Copy built-in presets to disk for customisation ([names...] [--dir d] [--force])")
+	fmt.Println("  config <cmd>     Manage user defaults: get <key> | set <key> [value] | list")
+	fmt.Println("  migrate          Update config files to the current schema (--check only reports, for CI)")
+
+	fmt.Println("\nExecution Flags:")
+	fmt.Println("  --env KEY=VAL    Override an environment variable (repeatable)")
+	fmt.Println("  --env-file path  Load overrides from a dotenv file (repeatable)")
+	fmt.Println("  --profile name   Select the profile used by `when` conditions (default: $MNGPROJ_PROFILE)")
+	fmt.Println("                   Flags after a literal -- are passed to the script untouched")
 
 	fmt.Println("\nCustom Scripts:")
 	fmt.Println("  <script> <comp>  Run any custom script defined in mngproj.toml")
 	fmt.Println("                   (e.g., mngproj deploy api production)")
+	fmt.Println("  <script> [args]  Run a script from [project.scripts] in the project root")
+	fmt.Println("                   (e.g., mngproj bootstrap)")
 }
 
+// ParseEnvFlags extracts --env KEY=VAL and --env-file path (also in --flag=value form)
+// from args into m.ExtraEnv, and --profile name into m.Profile, and returns the remaining
+// arguments. Scanning stops at "--", which is dropped. Later flags win over earlier ones.
+// Component `when` conditions are re-evaluated afterwards, as they may depend on both.
+func ParseEnvFlags(m *manager.Manager, args []string) ([]string, error) {
+	var rest []string
+	profile := m.Profile
+	changed := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+
+		flag, value, hasValue := strings.Cut(arg, "=")
+		if flag != "--env" && flag != "--env-file" && flag != "--profile" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+		changed = true
+
+		if flag == "--profile" {
+			profile = value
+			continue
+		}
+
+		if m.ExtraEnv == nil {
+			m.ExtraEnv = make(map[string]string)
+		}
+		if flag == "--env-file" {
+			values, err := config.LoadDotenv(value)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range values {
+				m.ExtraEnv[k] = v
+			}
+			continue
+		}
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env %q, expected KEY=VAL", value)
+		}
+		m.ExtraEnv[key] = val
+	}
+	if changed {
+		if err := m.SetProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	return rest, nil
+}
+
+// HandleGenericScript runs a custom script. A project script runs with all arguments,
+// unless the first argument names a component that defines a script of the same name.
 func HandleGenericScript(m *manager.Manager, scriptName string, args []string) {
+	isProject, err := m.HasProjectScript(scriptName)
+	if err != nil {
+		log.Fatalf("Execution failed: %v", err)
+	}
+	if isProject && (len(args) == 0 || !componentDefines(m, args[0], scriptName)) {
+		if err := m.ExecuteScript(manager.ProjectScope, scriptName, args, nil, nil); err != nil {
+			log.Fatalf("Execution failed: %v", err)
+		}
+		return
+	}
 	if len(args) == 0 {
 		fmt.Printf("Unknown command '%s'.\n", scriptName)
 		fmt.Println("If this is a custom script, usage is: mngproj <script> <component> [args...]")
+		fmt.Println("or define it in [project.scripts] to run it without a component.")
 		PrintUsage()
 		os.Exit(1)
 	}
@@ -57,8 +151,18 @@ func HandleGenericScript(m *manager.Manager, scriptName string, args []string) {
 	}
 }
 
+// componentDefines reports whether name is a component with the given script
+func componentDefines(m *manager.Manager, name, script string) bool {
+	comp, err := m.ResolveComponent(name)
+	if err != nil {
+		return false
+	}
+	_, ok := comp.Scripts[script]
+	return ok
+}
+
 func HandleInit(args []string) {
-	targetType := "go"
+	targetType := ""
 	if len(args) > 0 {
 		targetType = args[0]
 	}
@@ -119,6 +223,11 @@ func HandleSync(m *manager.Manager, args []string) {
 		log.Fatalf("Tool validation failed: %v", err)
 	}
 
+	jobs, args, err := parseJobs(args, m.Global.Jobs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	var components []string
 	if len(args) > 0 {
 		components = args
@@ -126,16 +235,64 @@ func HandleSync(m *manager.Manager, args []string) {
 		components = m.ListComponents()
 	}
 
+	// Up to jobs components sync at once; the first failure stops new ones from starting
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	sem := make(chan struct{}, jobs)
 	for _, comp := range components {
-		fmt.Printf("Syncing component %q...\n", comp)
-		if err := m.SyncComponent(comp); err != nil {
-			log.Printf("Failed to sync component %q: %v\n", comp, err)
-			os.Exit(1)
+		sem <- struct{}{}
+		if failed.Load() {
+			break
 		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			fmt.Printf("Syncing component %q...\n", comp)
+			if err := m.SyncComponent(comp); err != nil {
+				log.Printf("Failed to sync component %q: %v\n", comp, err)
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() {
+		os.Exit(1)
 	}
 	fmt.Println("All synced.")
 }
 
+// parseJobs extracts -j N (also -jN, --jobs N and --jobs=N) from args. Without the
+// flag the user config's jobs setting applies, then 1.
+func parseJobs(args []string, configured int) (int, []string, error) {
+	jobs := max(configured, 1)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch {
+		case arg == "-j" || arg == "--jobs":
+			if i+1 >= len(args) {
+				return 0, nil, fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--jobs="):
+			value = strings.TrimPrefix(arg, "--jobs=")
+		case strings.HasPrefix(arg, "-j") && len(arg) > 2:
+			value = arg[2:]
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, nil, fmt.Errorf("invalid job count %q", value)
+		}
+		jobs = n
+	}
+	return jobs, rest, nil
+}
+
 func HandleUp(m *manager.Manager, args []string) {
 	targetComps := make(map[string]bool)
 
@@ -175,6 +332,21 @@ func HandleUp(m *manager.Manager, args []string) {
 		components = append(components, c)
 	}
 
+	// Check declared ports and assign auto ports before anything starts
+	if err := m.AllocatePorts(components); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Report every missing variable at once instead of letting components crash one by one
+	problems, err := m.CheckRequiredEnv("run", slices.Sorted(slices.Values(components))...)
+	if err == nil {
+		err = manager.RequiredEnvError(problems)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Printf("Starting %d components: %v\n", len(components), components)
 
 	var wg sync.WaitGroup
@@ -182,7 +354,8 @@ func HandleUp(m *manager.Manager, args []string) {
 		wg.Add(1)
 		go func(compName string) {
 			defer wg.Done()
-			pw := &utils.PrefixWriter{Prefix: compName, Writer: os.Stdout}
+			pw := m.LogWriter(compName)
+			defer pw.Close()
 			if err := m.ExecuteScript(compName, "run", nil, pw, pw); err != nil {
 				fmt.Fprintf(pw, "Error: %v\n", err)
 			}
@@ -250,9 +423,49 @@ func HandleRemove(m *manager.Manager, args []string) {
 
 func HandleLs(m *manager.Manager) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "Name\tType\tPath")
-	for _, c := range m.ProjectConfig.Components {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Type, c.Path)
+	fmt.Fprintln(w, "Name\tTypes\tPath")
+	for i := range m.ProjectConfig.Components {
+		c := &m.ProjectConfig.Components[i]
+		types, err := m.EffectiveTypes(c)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t(error: %v)\t%s\n", c.Name, err, c.Path)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, strings.Join(types, ","), c.Path)
+	}
+	w.Flush()
+}
+
+// HandleScripts lists the scripts of a component with their description and origin
+func HandleScripts(m *manager.Manager, args []string) {
+	var comp *manager.ResolvedComponent
+	switch {
+	case len(args) > 0:
+		var err error
+		if comp, err = m.ResolveComponent(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	case len(m.ProjectConfig.Project.Scripts) > 0:
+		// Without a component, list the project scripts
+		var err error
+		if comp, err = m.ProjectScripts(); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		fmt.Println("Please specify a component name.")
+		HandleLs(m)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Script\tSource\tDescription")
+	for _, name := range slices.Sorted(maps.Keys(comp.Scripts)) {
+		script := comp.Scripts[name]
+		description := script.Description
+		if description == "" {
+			description = strings.SplitN(strings.Join(script.Commands(), " && "), "\n", 2)[0]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, comp.ScriptSources[name], description)
 	}
 	w.Flush()
 }
@@ -273,7 +486,11 @@ func HandleLsproj() {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "Project Path\tProject Name")
 	for _, root := range projectRoots {
-		cfgPath := filepath.Join(root, "mngproj.toml")
+		cfgPath, err := manager.ConfigFileIn(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config for %q: %v\n", root, err)
+			continue
+		}
 		cfg, err := config.LoadProjectConfig(cfgPath)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config for %q: %v\n", root, err)
@@ -288,17 +505,392 @@ func HandleLsproj() {
 	w.Flush()
 }
 
+// queryComponent is the JSON shape emitted by `query`. Its keys are part of the CLI
+// output and stay fixed, independent of the config file tags of ComponentConfig.
+type queryComponent struct {
+	Name           string
+	Type           string
+	Types          []string
+	Path           string
+	Priority       int
+	Groups         []string
+	Dependencies   []string
+	Env            map[string]config.EnvValue
+	Scripts        map[string]config.Script
+	Params         map[string]any
+	EnvFiles       []string
+	Ports          map[string]any
+	RequiredEnv    []config.RequiredEnv
+	Resolution     *config.ResolutionConfig
+	Disabled       bool
+	When           string
+	Overrides      []config.Override
+	EffectiveTypes []string
+}
+
+func newQueryComponent(c config.ComponentConfig, effectiveTypes []string) queryComponent {
+	return queryComponent{
+		Name:           c.Name,
+		Type:           c.Type,
+		Types:          c.Types,
+		Path:           c.Path,
+		Priority:       c.Priority,
+		Groups:         c.Groups,
+		Dependencies:   c.Dependencies,
+		Env:            c.Env,
+		Scripts:        c.Scripts,
+		Params:         c.Params,
+		EnvFiles:       c.EnvFiles,
+		Ports:          c.Ports,
+		RequiredEnv:    c.RequiredEnv,
+		Resolution:     c.Resolution,
+		Disabled:       c.Disabled,
+		When:           c.When,
+		Overrides:      c.Overrides,
+		EffectiveTypes: effectiveTypes,
+	}
+}
+
 func HandleQuery(m *manager.Manager, args []string) {
+	var components []queryComponent
+	for i := range m.ProjectConfig.Components {
+		c := m.ProjectConfig.Components[i]
+		types, err := m.EffectiveTypes(&c)
+		if err != nil {
+			log.Fatalf("Failed to resolve types of %q: %v", c.Name, err)
+		}
+		components = append(components, newQueryComponent(c, types))
+	}
+
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(m.ProjectConfig.Components); err != nil {
+	if err := encoder.Encode(components); err != nil {
 		log.Fatalf("Failed to encode components: %v", err)
 	}
 }
 
+// HandleDoctor reports what would keep components from starting: missing tools and
+// unset or invalid required env variables. It exits non-zero when anything is found.
+func HandleDoctor(m *manager.Manager, args []string) {
+	components := args
+	if len(components) == 0 {
+		components = m.ListComponents()
+	}
+
+	var issues []string
+	if err := m.ValidateTools(); err != nil {
+		issues = append(issues, err.Error())
+	}
+	problems, err := m.CheckRequiredEnv("run", components...)
+	if err != nil {
+		issues = append(issues, err.Error())
+	}
+	for _, p := range problems {
+		issues = append(issues, p.String())
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("No problems found in %d components.\n", len(components))
+		return
+	}
+	fmt.Printf("Found %d problems:\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	os.Exit(1)
+}
+
 func HandleInfo(m *manager.Manager) {
 	fmt.Printf("Project: %s\n", m.ProjectConfig.Project.Name)
 	fmt.Printf("Root: %s\n", m.ProjectDir)
-	fmt.Printf("Presets: %s\n", m.PresetsDir)
+	fmt.Println("Presets:")
+	for _, layer := range m.PresetLayers() {
+		fmt.Printf("  %s\n", layer.Name)
+	}
 	fmt.Printf("Components: %d\n", len(m.ProjectConfig.Components))
-}
\ No newline at end of file
+}
+
+// HandlePresets manages the preset search path. It works with or without a project:
+// inside a project the presets/ directory next to mngproj.toml is used.
+func HandlePresets(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: mngproj presets <ls|eject> [arguments...]")
+		return
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	baseDir := cwd
+	if configPath, err := manager.FindConfigFile(cwd); err == nil {
+		baseDir = filepath.Dir(configPath)
+	}
+
+	switch args[0] {
+	case "ls":
+		list, err := config.NewPresetRegistry(manager.PresetSearchPath(baseDir)).List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "Name\tLayer\tPath")
+		for _, p := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Layer, p.Path)
+		}
+		w.Flush()
+	case "eject":
+		destDir := filepath.Join(baseDir, "presets")
+		force := false
+		var names []string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--force":
+				force = true
+			case "--dir":
+				if i+1 >= len(args) {
+					log.Fatal("--dir requires a path")
+				}
+				i++
+				destDir = args[i]
+			default:
+				names = append(names, args[i])
+			}
+		}
+		written, err := manager.EjectPresets(destDir, names, force)
+		for _, path := range written {
+			fmt.Printf("Wrote %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("Eject failed: %v", err)
+		}
+	default:
+		fmt.Printf("Unknown presets command %q. Use ls or eject.\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// HandleMigrate rewrites the project's config files to the current schema.
+// With --check it only reports pending migrations and exits non-zero when there are any.
+func HandleMigrate(args []string) {
+	check := slices.Contains(args, "--check")
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The outdated-schema warning is what this command resolves
+	m, err := manager.NewWithOptions(cwd, manager.Options{Warnings: os.Stderr, IgnoreSchema: true})
+	if err != nil {
+		log.Fatalf("Migrate: %v", err)
+	}
+
+	changes, err := m.Migrate(!check)
+	if err != nil {
+		log.Fatalf("Migrate: %v", err)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("Config is up to date (schema %d).\n", config.CurrentSchema)
+		return
+	}
+	for _, c := range changes {
+		rel, err := filepath.Rel(cwd, c.Path)
+		if err != nil {
+			rel = c.Path
+		}
+		fmt.Printf("%s:\n", rel)
+		for _, step := range c.Steps {
+			fmt.Printf("  - %s\n", step)
+		}
+	}
+	if check {
+		fmt.Printf("%d file(s) need migrating; run `mngproj migrate`.\n", len(changes))
+		os.Exit(1)
+	}
+	fmt.Printf("Migrated %d file(s) to schema %d.\n", len(changes), config.CurrentSchema)
+}
+
+// HandleConfig manages the user config (~/.config/mngproj/config.toml)
+func HandleConfig(args []string) {
+	usage := "Usage: mngproj config get <key> | set <key> [value] | list"
+	if len(args) == 0 {
+		fmt.Println(usage)
+		return
+	}
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		if path, err := config.GlobalConfigPath(); err == nil {
+			fmt.Printf("# %s\n", path)
+		}
+		for _, key := range config.GlobalConfigKeys {
+			value, _ := cfg.Get(key)
+			fmt.Printf("%s = %s\n", key, value)
+		}
+	case "get":
+		if len(args) != 2 {
+			fmt.Println(usage)
+			os.Exit(1)
+		}
+		value, err := cfg.Get(args[1])
+		if err != nil {
+			log.Fatalf("Config: %v", err)
+		}
+		fmt.Println(value)
+	case "set":
+		// Without a value the setting is reset to its default
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println(usage)
+			os.Exit(1)
+		}
+		value := ""
+		if len(args) == 3 {
+			value = args[2]
+		}
+		if err := cfg.Set(args[1], value); err != nil {
+			log.Fatalf("Config: %v", err)
+		}
+		if err := config.SaveGlobalConfig(cfg); err != nil {
+			log.Fatalf("Config: %v", err)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
+
+// HandleSecrets manages the encrypted secrets store. Components reference secrets
+// from env as KEY = "secret:name"; project scripts use the reserved "project" scope.
+func HandleSecrets(m *manager.Manager, args []string) {
+	usage := "Usage: mngproj secrets set|get|rm <component|project> KEY [VALUE] | ls [component|project]"
+	if len(args) == 0 {
+		fmt.Println(usage)
+		return
+	}
+	sub := args[0]
+	if sub != "ls" && len(args) < 3 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	// Secrets of project scripts are kept under the reserved project scope
+	if sub != "ls" && args[1] != manager.ProjectScope && !slices.Contains(m.ListComponents(), args[1]) {
+		log.Fatalf("component %q not found", args[1])
+	}
+
+	store, err := m.OpenSecrets(sub == "set")
+	if err != nil {
+		log.Fatalf("Secrets: %v", err)
+	}
+
+	switch sub {
+	case "set":
+		comp, key := args[1], args[2]
+		var value string
+		if len(args) > 3 {
+			value = strings.Join(args[3:], " ")
+		} else {
+			// Reading from stdin keeps the value out of the shell history
+			fmt.Fprintf(os.Stderr, "Value for %s/%s: ", comp, key)
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				log.Fatalf("Failed to read value: %v", err)
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+		store.Set(comp, key, value)
+		if err := store.Save(); err != nil {
+			log.Fatalf("Secrets: %v", err)
+		}
+		fmt.Printf("Stored secret %q for component %q. Reference it as \"secret:%s\".\n", key, comp, key)
+	case "get":
+		value, ok := store.Get(args[1], args[2])
+		if !ok {
+			log.Fatalf("secret %q is not set for component %q", args[2], args[1])
+		}
+		fmt.Println(value)
+	case "rm":
+		if !store.Delete(args[1], args[2]) {
+			log.Fatalf("secret %q is not set for component %q", args[2], args[1])
+		}
+		if err := store.Save(); err != nil {
+			log.Fatalf("Secrets: %v", err)
+		}
+		fmt.Printf("Removed secret %q from component %q.\n", args[2], args[1])
+	case "ls":
+		comps := store.Components()
+		if len(args) > 1 {
+			comps = []string{args[1]}
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "Component\tKey")
+		for _, c := range comps {
+			for _, key := range store.Names(c) {
+				fmt.Fprintf(w, "%s\t%s\n", c, key)
+			}
+		}
+		w.Flush()
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
+
+// HandleDetect scans a directory tree and proposes components from the presets' detection rules.
+// It works with or without a project; with --write the proposals are added to the project config.
+func HandleDetect(args []string) {
+	dir := "."
+	write := false
+	for _, arg := range args {
+		if arg == "--write" {
+			write = true
+		} else {
+			dir = arg
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reg := config.NewPresetRegistry(manager.PresetSearchPath(dir))
+	var rolePriority map[string]int
+	if m, err := manager.New(dir); err == nil {
+		reg = m.Presets()
+		rolePriority = m.ProjectConfig.Resolution.RolePriority
+	}
+	detector, err := manager.NewDetector(reg, rolePriority, os.Stderr)
+	if err != nil {
+		log.Fatalf("Failed to load detection rules: %v", err)
+	}
+	comps, err := detector.Scan(dir)
+	if err != nil {
+		log.Fatalf("Detection failed: %v", err)
+	}
+	if len(comps) == 0 {
+		fmt.Printf("No components detected in %q\n", dir)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Name\tTypes\tPath")
+	for _, c := range comps {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, strings.Join(c.Types, ", "), c.Path)
+	}
+	w.Flush()
+
+	if !write {
+		return
+	}
+	added, err := manager.WriteDetectedComponents(dir, comps)
+	if err != nil {
+		log.Fatalf("Failed to write components: %v", err)
+	}
+	if len(added) == 0 {
+		fmt.Println("All detected components are already part of the project")
+		return
+	}
+	fmt.Printf("Added components: %s\n", strings.Join(added, ", "))
+}
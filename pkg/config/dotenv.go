@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadDotenv reads and parses a dotenv file
+func LoadDotenv(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	env, err := ParseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+	return env, nil
+}
+
+// ParseDotenv parses dotenv content with the usual semantics:
+//   - blank lines and lines starting with # are ignored, an optional `export ` prefix is allowed
+//   - 'single quoted' values are literal and may span lines
+//   - "double quoted" values may span lines, support \n \t \r \" \\ \$ escapes and ${VAR} expansion
+//   - unquoted values are trimmed, may end with a ` # comment` and support ${VAR} expansion
+//
+// Variables are expanded from keys defined earlier in the file, then from the process environment.
+func ParseDotenv(content string) (map[string]string, error) {
+	env := make(map[string]string)
+	lookup := func(key string) string {
+		if v, ok := env[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	}
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lineNo := 0
+	for len(content) > 0 {
+		var line string
+		line, content = cutLine(content)
+		lineNo++
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		trimmed = strings.TrimPrefix(trimmed, "export ")
+
+		key, rest, ok := strings.Cut(trimmed, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		switch {
+		case strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`):
+			quote := rest[0]
+			expand := func(s string) string { return s }
+			if quote == '"' {
+				expand = func(s string) string { return os.Expand(s, lookup) }
+			}
+			value, remaining, consumed, err := readQuoted(rest[1:], content, quote, expand)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			content = remaining
+			lineNo += consumed
+			env[key] = value
+		default:
+			if i := strings.Index(rest, " #"); i >= 0 {
+				rest = rest[:i]
+			}
+			env[key] = os.Expand(strings.TrimSpace(rest), lookup)
+		}
+	}
+	return env, nil
+}
+
+func cutLine(s string) (line, rest string) {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// readQuoted reads a quoted value starting after the opening quote. When the closing
+// quote is not on the current line, following lines are consumed from remaining.
+// expand is applied to the text between escapes, so an escaped \$ stays literal.
+func readQuoted(current, remaining string, quote byte, expand func(string) string) (value, rest string, consumed int, err error) {
+	var b, seg strings.Builder
+	flush := func() {
+		b.WriteString(expand(seg.String()))
+		seg.Reset()
+	}
+	text := current
+	for {
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			if quote == '"' && c == '\\' && i+1 < len(text) {
+				flush()
+				i++
+				switch text[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(text[i])
+				}
+				continue
+			}
+			if c == quote {
+				tail := strings.TrimSpace(text[i+1:])
+				if tail != "" && !strings.HasPrefix(tail, "#") {
+					return "", "", 0, fmt.Errorf("unexpected characters after closing quote")
+				}
+				flush()
+				return b.String(), remaining, consumed, nil
+			}
+			seg.WriteByte(c)
+		}
+		if remaining == "" {
+			return "", "", 0, fmt.Errorf("unterminated quoted value")
+		}
+		seg.WriteByte('\n')
+		text, remaining = cutLine(remaining)
+		consumed++
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Cache modes of command env values
+const (
+	EnvCacheNone = ""    // Run the command every time a script is prepared
+	EnvCacheRun  = "run" // Run the command once per mngproj invocation
+)
+
+// EnvValue is an environment entry. In config files it is either a plain string or
+// a table. A command computes the value when a script is prepared, and merge
+// operators extend the value inherited from earlier presets or the process:
+//
+//	GIT_SHA = { cmd = "git rev-parse HEAD", cache = "run", timeout = "5s" }
+//	PATH = { prepend = "${MNGPROJ_COMPONENT_ROOT}/node_modules/.bin" }
+type EnvValue struct {
+	Value   string `toml:"value,omitempty" json:"value,omitempty" yaml:"value,omitempty"`
+	Cmd     string `toml:"cmd,omitempty" json:"cmd,omitempty" yaml:"cmd,omitempty"`             // Run in the component directory; its trimmed stdout is the value
+	Cache   string `toml:"cache,omitempty" json:"cache,omitempty" yaml:"cache,omitempty"`       // "" or "run"
+	Timeout string `toml:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"` // Duration such as "5s"; defaults to 10s
+
+	Prepend string `toml:"prepend,omitempty" json:"prepend,omitempty" yaml:"prepend,omitempty"` // Added in front, separated by the OS path list separator
+	Append  string `toml:"append,omitempty" json:"append,omitempty" yaml:"append,omitempty"`    // Added at the end, separated by the OS path list separator
+	Default string `toml:"default,omitempty" json:"default,omitempty" yaml:"default,omitempty"` // Used when the variable is unset or empty
+
+	When string `toml:"when,omitempty" json:"when,omitempty" yaml:"when,omitempty"` // Condition for the entry to apply, e.g. "env.CI == 'true'"
+}
+
+// envValueFields avoids recursing into the custom unmarshalers
+type envValueFields EnvValue
+
+// isPlain reports whether the value can be written as a string
+func (v EnvValue) isPlain() bool {
+	return v.Cmd == "" && v.Cache == "" && v.Timeout == "" && v.Prepend == "" && v.Append == "" && v.Default == "" && v.When == ""
+}
+
+// IsModifier reports whether the entry builds on the inherited value instead of setting one
+func (v EnvValue) IsModifier() bool {
+	return v.Value == "" && v.Cmd == "" && (v.Prepend != "" || v.Append != "" || v.Default != "")
+}
+
+// MergeEnv applies the entries of src onto dst in place. Values and commands replace
+// earlier entries; modifiers are combined with them, so that later prepends end up in
+// front, later appends at the end and a default only fills an entry without a value.
+func MergeEnv(dst, src map[string]EnvValue) {
+	for k, next := range src {
+		cur, ok := dst[k]
+		if !ok || !next.IsModifier() {
+			dst[k] = next
+			continue
+		}
+		if cur.IsModifier() && cur.Default == "" {
+			cur.Default = next.Default
+		}
+		cur.Prepend = JoinPathList(next.Prepend, cur.Prepend)
+		cur.Append = JoinPathList(cur.Append, next.Append)
+		dst[k] = cur
+	}
+}
+
+// JoinPathList joins the non-empty parts with the OS path list separator
+func JoinPathList(parts ...string) string {
+	parts = slices.DeleteFunc(slices.Clone(parts), func(s string) bool { return s == "" })
+	return strings.Join(parts, string(os.PathListSeparator))
+}
+
+// UnmarshalTOML accepts `NAME = "value"` and inline tables. Standard tables are decoded field by field.
+func (v *EnvValue) UnmarshalTOML(node *unstable.Node) error {
+	return decodeNode(node, v)
+}
+
+func (v *EnvValue) UnmarshalJSON(data []byte) error {
+	*v = EnvValue{}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), `"`) {
+		return json.Unmarshal(data, &v.Value)
+	}
+	return decodeFields(data, (*envValueFields)(v))
+}
+
+func (v EnvValue) MarshalJSON() ([]byte, error) {
+	if v.isPlain() {
+		return json.Marshal(v.Value)
+	}
+	return json.Marshal(envValueFields(v))
+}
+
+func (v *EnvValue) UnmarshalYAML(node *yaml.Node) error {
+	*v = EnvValue{}
+	if node.Kind == yaml.ScalarNode {
+		v.Value = node.Value
+		return nil
+	}
+	return decodeYAMLFields(node, (*envValueFields)(v))
+}
+
+func (v EnvValue) MarshalYAML() (any, error) {
+	if v.isPlain() {
+		return v.Value, nil
+	}
+	return envValueFields(v), nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames lists the accepted project config file names, in lookup order
+var ConfigFileNames = []string{"mngproj.toml", "mngproj.yaml", "mngproj.yml", "mngproj.json", "mngproj.star"}
+
+// PresetExtensions lists the accepted preset file extensions
+var PresetExtensions = []string{".toml", ".yaml", ".yml", ".json"}
+
+// Format identifies a config serialization, derived from the file extension
+type Format string
+
+const (
+	FormatTOML Format = "toml"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	// FormatStarlark configs are programs evaluated by LoadProjectConfig; they cannot be decoded or written
+	FormatStarlark Format = "starlark"
+)
+
+// FormatOf returns the format of a config file based on its extension
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".star":
+		return FormatStarlark, nil
+	}
+	return "", fmt.Errorf("unsupported config format: %s", path)
+}
+
+// decode unmarshals data of the given file into v according to its extension
+func decode(path string, data []byte, v any) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatStarlark:
+		return fmt.Errorf("%s is a Starlark program and cannot be decoded", path)
+	case FormatYAML:
+		return yaml.Unmarshal(data, v)
+	case FormatJSON:
+		return json.Unmarshal(data, v)
+	default:
+		// The unmarshaler interface lets values such as scripts be written as strings or inline tables
+		err := toml.NewDecoder(bytes.NewReader(data)).EnableUnmarshalerInterface().DisallowUnknownFields().Decode(v)
+		return entryFieldErrors(err)
+	}
+}
+
+// entryFieldErrors keeps the unknown keys of strict decoding that are fields of a
+// script, env or required_env entry written as a standard table, e.g. a misspelled
+// `whne` in [components.scripts.migrate]. Inline tables are checked by decodeFields;
+// other unknown keys are still ignored.
+func entryFieldErrors(err error) error {
+	var strict *toml.StrictMissingError
+	if !errors.As(err, &strict) {
+		return err
+	}
+	var problems []string
+	for _, e := range strict.Errors {
+		key := e.Key()
+		if !isEntryField(key) {
+			continue
+		}
+		row, _ := e.Position()
+		problems = append(problems, fmt.Sprintf("line %d: unknown field %q in %s", row, key[len(key)-1], strings.Join(key[:len(key)-1], ".")))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// isEntryField reports whether key names a field of a script, env or required_env entry
+func isEntryField(key []string) bool {
+	for i, k := range key {
+		switch {
+		case (k == "scripts" || k == "env") && len(key) == i+3:
+			return true
+		case k == "required_env" && len(key) == i+2:
+			return true
+		}
+	}
+	return false
+}
+
+// encode marshals v in the format matching the file extension of path
+func encode(path string, v any) ([]byte, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatStarlark:
+		return nil, fmt.Errorf("%s is generated by a Starlark program; edit it instead", path)
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case FormatJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		return append(data, '\n'), err
+	default:
+		return toml.Marshal(v)
+	}
+}
+
+// DecodeFile reads a config file of any format into a generic map, as used by migrations
+func DecodeFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := decode(path, data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+	return doc, nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// GlobalConfig holds personal defaults from the user's config.toml. Project config
+// and command-line flags take precedence over it.
+type GlobalConfig struct {
+	PresetDirs []string `toml:"preset_dirs,omitempty"` // Extra preset directories, searched after the user preset dir
+	Color      string   `toml:"color,omitempty"`       // Colored log prefixes: auto (default), always or never
+	Jobs       int      `toml:"jobs,omitempty"`        // Default concurrency for `sync`, overridden by -j
+	LogFormat  string   `toml:"log_format,omitempty"`  // Aggregated log output of up/watch: text (default) or json
+	InitType   string   `toml:"init_type,omitempty"`   // Type used by `init` when none is given or detected
+}
+
+// GlobalConfigKeys lists the settings accepted by GlobalConfig.Get and Set
+var GlobalConfigKeys = []string{"preset_dirs", "color", "jobs", "log_format", "init_type"}
+
+// GlobalConfigPath returns the location of the user config: mngproj/config.toml in
+// the user config directory ($XDG_CONFIG_HOME or ~/.config on Linux)
+func GlobalConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mngproj", "config.toml"), nil
+}
+
+// LoadGlobalConfig reads the user config. A missing file yields an empty config.
+func LoadGlobalConfig() (*GlobalConfig, error) {
+	var cfg GlobalConfig
+	path, err := GlobalConfigPath()
+	if err != nil {
+		return &cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user config: %w", err)
+	}
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse user config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// SaveGlobalConfig writes the user config, creating its directory when needed
+func SaveGlobalConfig(cfg *GlobalConfig) error {
+	path, err := GlobalConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := toml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write user config: %w", err)
+	}
+	return nil
+}
+
+// Get returns a setting formatted for display; lists are separated by the OS path list separator
+func (c *GlobalConfig) Get(key string) (string, error) {
+	switch key {
+	case "preset_dirs":
+		return strings.Join(c.PresetDirs, string(filepath.ListSeparator)), nil
+	case "color":
+		return c.Color, nil
+	case "jobs":
+		if c.Jobs == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(c.Jobs), nil
+	case "log_format":
+		return c.LogFormat, nil
+	case "init_type":
+		return c.InitType, nil
+	}
+	return "", fmt.Errorf("unknown setting %q (known: %s)", key, strings.Join(GlobalConfigKeys, ", "))
+}
+
+// Set validates and stores a setting given as text; an empty value resets it
+func (c *GlobalConfig) Set(key, value string) error {
+	switch key {
+	case "preset_dirs":
+		c.PresetDirs = nil
+		for _, dir := range filepath.SplitList(value) {
+			if dir != "" {
+				c.PresetDirs = append(c.PresetDirs, dir)
+			}
+		}
+	case "color":
+		if value != "" && value != "auto" && value != "always" && value != "never" {
+			return fmt.Errorf("color must be auto, always or never, got %q", value)
+		}
+		c.Color = value
+	case "jobs":
+		if value == "" {
+			c.Jobs = 0
+			return nil
+		}
+		jobs, err := strconv.Atoi(value)
+		if err != nil || jobs < 1 {
+			return fmt.Errorf("jobs must be a positive number, got %q", value)
+		}
+		c.Jobs = jobs
+	case "log_format":
+		if value != "" && value != "text" && value != "json" {
+			return fmt.Errorf("log_format must be text or json, got %q", value)
+		}
+		c.LogFormat = value
+	case "init_type":
+		c.InitType = value
+	default:
+		return fmt.Errorf("unknown setting %q (known: %s)", key, strings.Join(GlobalConfigKeys, ", "))
+	}
+	return nil
+}
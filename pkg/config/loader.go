@@ -3,22 +3,29 @@ package config
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
-
-	"github.com/pelletier/go-toml/v2"
 )
 
-// LoadProjectConfig reads and parses mngproj.toml from the given path
+// LoadProjectConfig reads and parses a project config (mngproj.toml, .yaml/.yml or .json) from the given path.
+// A mngproj.star file is evaluated instead and yields the same structure.
 func LoadProjectConfig(path string) (*ProjectConfig, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
-
 	var cfg ProjectConfig
-	if err := toml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if format, _ := FormatOf(path); format == FormatStarlark {
+		generated, err := loadStarlarkConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = *generated
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := decode(path, data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if err := checkSchema(path, &cfg); err != nil {
+		return nil, err
 	}
 
 	// Validate component names for duplicates
@@ -41,62 +48,47 @@ func LoadProjectConfig(path string) (*ProjectConfig, error) {
 	return &cfg, nil
 }
 
-// LoadPreset loads a preset configuration by type name
-// It prioritizes {type}_{GOOS}.toml, then falls back to {type}.toml
-func LoadPreset(presetsDir, typeName string) (*PresetConfig, error) {
-	// 1. Try OS-specific preset
-	osSpecificName := fmt.Sprintf("%s_%s.toml", typeName, runtime.GOOS)
-	if preset, err := findAndLoadPreset(presetsDir, osSpecificName); err == nil {
-		return preset, nil
+// SaveProjectConfig writes the project configuration to the specified path,
+// in the format given by its extension
+func SaveProjectConfig(path string, cfg *ProjectConfig) error {
+	data, err := encode(path, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	// 2. Fallback to standard preset
-	return findAndLoadPreset(presetsDir, fmt.Sprintf("%s.toml", typeName))
-}
-
-func findAndLoadPreset(presetsDir, filename string) (*PresetConfig, error) {
-	var foundPath string
-	err := filepath.WalkDir(presetsDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !d.IsDir() && d.Name() == filename {
-			foundPath = path
-			return os.ErrExist // Signal to stop walking
-		}
-		return nil
-	})
-
-	if err != nil && err != os.ErrExist {
-		return nil, fmt.Errorf("error searching for preset: %w", err)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
-	if foundPath == "" {
-		return nil, fmt.Errorf("preset file %q not found in %s", filename, presetsDir)
-	}
+	return nil
+}
+
+// ComponentFileNames lists the accepted per-directory component fragment names, in lookup order
+var ComponentFileNames = []string{"component.toml", "component.yaml", "component.yml", "component.json"}
 
-	data, err := os.ReadFile(foundPath)
+// LoadComponentFragment reads a component fragment (component.toml, .yaml/.yml or .json)
+func LoadComponentFragment(path string) (*ComponentConfig, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read preset file %s: %w", foundPath, err)
+		return nil, fmt.Errorf("failed to read component file: %w", err)
 	}
 
-	var preset PresetConfig
-	if err := toml.Unmarshal(data, &preset); err != nil {
-		return nil, fmt.Errorf("failed to parse preset file: %w", err)
+	var comp ComponentConfig
+	if err := decode(path, data, &comp); err != nil {
+		return nil, fmt.Errorf("failed to parse component file %s: %w", path, err)
 	}
-
-	return &preset, nil
+	return &comp, nil
 }
 
-// SaveProjectConfig writes the project configuration to the specified path
-func SaveProjectConfig(path string, cfg *ProjectConfig) error {
-	data, err := toml.Marshal(cfg)
+// SaveComponentFragment writes a component fragment in the format given by its extension
+func SaveComponentFragment(path string, comp *ComponentConfig) error {
+	data, err := encode(path, comp)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return fmt.Errorf("failed to marshal component: %w", err)
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+		return fmt.Errorf("failed to write component file: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+)
+
+// LocalConfigFileNames lists the accepted per-developer override file names, in lookup order.
+// They sit next to the project config and are not meant to be committed.
+var LocalConfigFileNames = []string{"mngproj.local.toml", "mngproj.local.yaml", "mngproj.local.yml", "mngproj.local.json"}
+
+// LoadLocalConfig reads a local override file. Unlike LoadProjectConfig it fills in
+// no defaults, so that only the values written in the file are merged and saved.
+func LoadLocalConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local config file: %w", err)
+	}
+
+	var cfg ProjectConfig
+	if err := decode(path, data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse local config file %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range cfg.Components {
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate component name found in %s: %q", path, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return &cfg, nil
+}
+
+// SetLocalDependencies sets the dependencies of component in the local override file
+// at path, leaving every other key as written. TOML files are edited in place; other
+// formats, and TOML shapes the in-place edit does not handle, are re-encoded from
+// their generic form, so that no defaults or empty values are added.
+func SetLocalDependencies(path, component string, deps []string) error {
+	doc, err := DecodeFile(path)
+	if err != nil {
+		return err
+	}
+	list := make([]any, len(deps))
+	for i, d := range deps {
+		list[i] = d
+	}
+	found := false
+	comps, _ := doc["components"].([]any)
+	for _, c := range comps {
+		if comp, ok := c.(map[string]any); ok && comp["name"] == component {
+			comp["dependencies"] = list
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("component %q not found in %s", component, path)
+	}
+
+	data, err := encode(path, doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if format, _ := FormatOf(path); format == FormatTOML {
+		if edited, err := setTOMLDependencies(path, component, list); err == nil {
+			var check map[string]any
+			if decode(path, edited, &check) == nil && sameJSON(check, doc) {
+				data = edited
+			}
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// setTOMLDependencies rewrites the dependencies of component in the TOML file at path
+func setTOMLDependencies(path, component string, deps []any) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseTOMLDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, comp := range doc.arraySections("components") {
+		if name := comp.get("name"); name == nil || name.value != component {
+			continue
+		}
+		if existing := comp.get("dependencies"); existing != nil {
+			doc.replace(existing, "dependencies", deps)
+		} else {
+			doc.insert(comp, "dependencies = "+formatTOMLValue(deps)+"\n")
+		}
+	}
+	return doc.bytes(), nil
+}
+
+// MergeLocalConfig deep-merges local over cfg. Maps are merged key by key and
+// components are matched by name, components unknown to cfg being added. Scripts,
+// env values, non-empty lists and other values set in local replace those of cfg.
+func MergeLocalConfig(cfg, local *ProjectConfig) {
+	mergeValue(reflect.ValueOf(cfg).Elem(), reflect.ValueOf(local).Elem())
+}
+
+var (
+	scriptType     = reflect.TypeOf(Script{})
+	envValueType   = reflect.TypeOf(EnvValue{})
+	componentsType = reflect.TypeOf([]ComponentConfig(nil))
+)
+
+// mergeValue merges src into the settable value dst
+func mergeValue(dst, src reflect.Value) {
+	switch {
+	case src.Type() == scriptType, src.Type() == envValueType:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	case src.Type() == componentsType:
+		for _, local := range src.Interface().([]ComponentConfig) {
+			i := slices.IndexFunc(dst.Interface().([]ComponentConfig), func(c ComponentConfig) bool { return c.Name == local.Name })
+			if i < 0 {
+				if local.Path == "" {
+					local.Path = "."
+				}
+				dst.Set(reflect.Append(dst, reflect.ValueOf(local)))
+				continue
+			}
+			mergeValue(dst.Index(i), reflect.ValueOf(local))
+		}
+	case src.Kind() == reflect.Struct:
+		for i := range src.NumField() {
+			if src.Type().Field(i).IsExported() {
+				mergeValue(dst.Field(i), src.Field(i))
+			}
+		}
+	case src.Kind() == reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(src.Type()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			merged := reflect.New(src.Type().Elem()).Elem()
+			if existing := dst.MapIndex(iter.Key()); existing.IsValid() {
+				merged.Set(existing)
+				mergeValue(merged, iter.Value())
+			} else {
+				merged.Set(iter.Value())
+			}
+			dst.SetMapIndex(iter.Key(), merged)
+		}
+	case src.Kind() == reflect.Interface:
+		// Nested tables, e.g. in params, are merged; anything else is replaced
+		if src.IsNil() {
+			return
+		}
+		if !dst.IsNil() && dst.Elem().Kind() == reflect.Map && dst.Elem().Type() == src.Elem().Type() {
+			merged := reflect.New(dst.Elem().Type()).Elem()
+			merged.Set(reflect.MakeMap(dst.Elem().Type()))
+			iter := dst.Elem().MapRange()
+			for iter.Next() {
+				merged.SetMapIndex(iter.Key(), iter.Value())
+			}
+			mergeValue(merged, src.Elem())
+			dst.Set(merged)
+			return
+		}
+		dst.Set(src)
+	case src.Kind() == reflect.Slice:
+		// An empty list, e.g. `types = []` from an encoder, does not wipe the shared one
+		if src.Len() > 0 {
+			dst.Set(src)
+		}
+	default:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PresetLayer is one location searched for presets: a directory on disk or an embedded tree
+type PresetLayer struct {
+	Name string // Display name, e.g. the directory path or "embedded"
+	FS   fs.FS
+}
+
+// DirLayer returns a preset layer backed by a directory on disk
+func DirLayer(dir string) PresetLayer {
+	return PresetLayer{Name: dir, FS: os.DirFS(dir)}
+}
+
+// PresetInfo describes where a preset name is found in the search path
+type PresetInfo struct {
+	Name  string
+	Layer string // Layer providing the effective definition
+	Path  string // Path of the file inside the layer
+}
+
+// PresetRegistry indexes a preset search path once and caches the flattened presets.
+// It is safe for concurrent use. Presets returned by Load are shared and must not be modified.
+type PresetRegistry struct {
+	layers []PresetLayer
+
+	mu      sync.Mutex
+	indexed bool
+	index   []map[string][]string // Per layer: file name without extension -> paths inside the layer
+	cache   map[string]*PresetConfig
+}
+
+// NewPresetRegistry creates a registry over an ordered search path. Earlier layers win.
+func NewPresetRegistry(layers []PresetLayer) *PresetRegistry {
+	return &PresetRegistry{layers: layers, cache: make(map[string]*PresetConfig)}
+}
+
+// LoadPreset loads a preset configuration by type name
+// It prioritizes {type}_{GOOS}.toml, then falls back to {type}.toml
+// (.yaml, .yml and .json presets are accepted as well)
+// Presets listed in `extends` are resolved recursively and flattened into the result.
+func LoadPreset(presetsDir, typeName string) (*PresetConfig, error) {
+	return LoadPresetFromLayers([]PresetLayer{DirLayer(presetsDir)}, typeName)
+}
+
+// LoadPresetFromLayers loads a single preset from an ordered search path without caching
+func LoadPresetFromLayers(layers []PresetLayer, typeName string) (*PresetConfig, error) {
+	return NewPresetRegistry(layers).Load(typeName)
+}
+
+// Load returns the flattened preset for typeName. Parents named in `extends`
+// are looked up in the same search path.
+func (r *PresetRegistry) Load(typeName string) (*PresetConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.buildIndex(); err != nil {
+		return nil, err
+	}
+	return r.load(typeName, nil)
+}
+
+// List returns every preset visible in the search path. A name defined in
+// several layers is reported once, for the highest layer.
+func (r *PresetRegistry) List() ([]PresetInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.buildIndex(); err != nil {
+		return nil, err
+	}
+
+	var list []PresetInfo
+	seen := make(map[string]bool)
+	for i, files := range r.index {
+		for name, paths := range files {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			list = append(list, PresetInfo{Name: name, Layer: r.layers[i].Name, Path: strings.Join(paths, ", ")})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (r *PresetRegistry) buildIndex() error {
+	if r.indexed {
+		return nil
+	}
+	r.index = make([]map[string][]string, len(r.layers))
+	for i, layer := range r.layers {
+		files := make(map[string][]string)
+		err := fs.WalkDir(layer.FS, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := path.Ext(p); !d.IsDir() && slices.Contains(PresetExtensions, ext) {
+				name := strings.TrimSuffix(d.Name(), ext)
+				files[name] = append(files[name], p)
+			}
+			return nil
+		})
+		// Missing layer directories are simply empty
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error indexing presets in %s: %w", layer.Name, err)
+		}
+		r.index[i] = files
+	}
+	r.indexed = true
+	return nil
+}
+
+func (r *PresetRegistry) load(typeName string, chain []string) (*PresetConfig, error) {
+	if preset, ok := r.cache[typeName]; ok {
+		return preset, nil
+	}
+	for _, t := range chain {
+		if t == typeName {
+			return nil, fmt.Errorf("preset inheritance cycle: %s -> %s", strings.Join(chain, " -> "), typeName)
+		}
+	}
+	chain = append(chain, typeName)
+
+	preset, err := r.loadFile(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(preset.Extends) > 0 {
+		// Parents are applied in order, then the preset itself overrides them
+		flat := &PresetConfig{}
+		for _, parentName := range preset.Extends {
+			parent, err := r.load(parentName, chain)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load parent preset %q of %q: %w", parentName, typeName, err)
+			}
+			mergePreset(flat, parent)
+		}
+		mergePreset(flat, preset)
+		flat.Extends = preset.Extends
+		flat.Metadata.Detect = preset.Metadata.Detect
+		preset = flat
+	}
+
+	r.cache[typeName] = preset
+	return preset, nil
+}
+
+func (r *PresetRegistry) loadFile(typeName string) (*PresetConfig, error) {
+	candidates := []string{
+		fmt.Sprintf("%s_%s", typeName, runtime.GOOS), // 1. OS-specific preset
+		typeName, // 2. Fallback to standard preset
+	}
+
+	var searched []string
+	for i, layer := range r.layers {
+		for _, filename := range candidates {
+			paths := r.index[i][filename]
+			if len(paths) == 0 {
+				continue
+			}
+			if len(paths) > 1 {
+				return nil, fmt.Errorf("preset %q is ambiguous in %s: found %s", typeName, layer.Name, strings.Join(paths, " and "))
+			}
+			return parsePresetFile(layer, paths[0])
+		}
+		searched = append(searched, layer.Name)
+	}
+	return nil, fmt.Errorf("preset %q not found in %s", typeName, strings.Join(searched, ", "))
+}
+
+func parsePresetFile(layer PresetLayer, p string) (*PresetConfig, error) {
+	data, err := fs.ReadFile(layer.FS, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preset file %s: %w", path.Join(layer.Name, p), err)
+	}
+
+	var preset PresetConfig
+	if err := decode(p, data, &preset); err != nil {
+		return nil, fmt.Errorf("failed to parse preset file %s: %w", path.Join(layer.Name, p), err)
+	}
+	preset.Metadata.Gitignore = appendUnique(preset.Metadata.Gitignore, preset.Gitignore...)
+	preset.Gitignore = nil
+	return &preset, nil
+}
+
+// mergePreset overlays src onto dst. Scripts and params are overridden per key, env entries are merged with MergeEnv,
+// gitignore patterns, overrides, required env, declared roles, tools, requirements and conflicts are accumulated and non-empty metadata fields win.
+func mergePreset(dst, src *PresetConfig) {
+	if dst.Scripts == nil {
+		dst.Scripts = make(map[string]Script)
+	}
+	for k, v := range src.Scripts {
+		dst.Scripts[k] = v
+	}
+	if dst.Env == nil {
+		dst.Env = make(map[string]EnvValue)
+	}
+	MergeEnv(dst.Env, src.Env)
+	if dst.Params == nil {
+		dst.Params = make(map[string]any)
+	}
+	for k, v := range src.Params {
+		dst.Params[k] = v
+	}
+	dst.Metadata.Gitignore = appendUnique(dst.Metadata.Gitignore, src.Metadata.Gitignore...)
+	dst.Overrides = append(dst.Overrides, src.Overrides...)
+	dst.RequiredEnv = MergeRequiredEnv(dst.RequiredEnv, src.RequiredEnv...)
+
+	if src.Metadata.Type != "" {
+		dst.Metadata.Type = src.Metadata.Type
+	}
+	if src.Metadata.Role != "" {
+		dst.Metadata.Role = src.Metadata.Role
+	}
+	if len(src.Metadata.Roles) > 0 {
+		if dst.Metadata.Roles == nil {
+			dst.Metadata.Roles = make(map[string]int)
+		}
+		maps.Copy(dst.Metadata.Roles, src.Metadata.Roles)
+	}
+	if src.Metadata.Description != "" {
+		dst.Metadata.Description = src.Metadata.Description
+	}
+	if src.Metadata.ManifestFile != "" {
+		dst.Metadata.ManifestFile = src.Metadata.ManifestFile
+	}
+	dst.Metadata.RequiredTools = appendUnique(dst.Metadata.RequiredTools, src.Metadata.RequiredTools...)
+	dst.Metadata.Requires = appendUnique(dst.Metadata.Requires, src.Metadata.Requires...)
+	dst.Metadata.Conflicts = appendUnique(dst.Metadata.Conflicts, src.Metadata.Conflicts...)
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// RequiredEnv is an environment variable a component needs to start. In config files
+// it is either the variable name or a table:
+//
+//	required_env = ["DATABASE_URL", { name = "STRIPE_KEY", pattern = "sk_(test|live)_.+", description = "Stripe secret key" }]
+type RequiredEnv struct {
+	Name        string `toml:"name" json:"name" yaml:"name"`
+	Pattern     string `toml:"pattern,omitempty" json:"pattern,omitempty" yaml:"pattern,omitempty"` // Regular expression the whole value must match
+	Description string `toml:"description,omitempty" json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// requiredEnvFields avoids recursing into the custom unmarshalers
+type requiredEnvFields RequiredEnv
+
+// Check returns why value does not satisfy the requirement, or "" when it does.
+// Empty values count as missing.
+func (r RequiredEnv) Check(value string, ok bool) (string, error) {
+	if !ok || value == "" {
+		return "is not set", nil
+	}
+	if r.Pattern == "" {
+		return "", nil
+	}
+	re, err := r.compile()
+	if err != nil {
+		return "", err
+	}
+	if !re.MatchString(value) {
+		return fmt.Sprintf("does not match %q", r.Pattern), nil
+	}
+	return "", nil
+}
+
+// compile anchors the pattern so that it must match the whole value
+func (r RequiredEnv) compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("required env %s: invalid pattern %q: %w", r.Name, r.Pattern, err)
+	}
+	return re, nil
+}
+
+// UnmarshalTOML accepts plain names and inline tables. Standard tables are decoded field by field.
+func (r *RequiredEnv) UnmarshalTOML(node *unstable.Node) error {
+	return decodeNode(node, r)
+}
+
+func (r *RequiredEnv) UnmarshalJSON(data []byte) error {
+	*r = RequiredEnv{}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), `"`) {
+		return json.Unmarshal(data, &r.Name)
+	}
+	return decodeFields(data, (*requiredEnvFields)(r))
+}
+
+func (r RequiredEnv) MarshalJSON() ([]byte, error) {
+	if r.Pattern == "" && r.Description == "" {
+		return json.Marshal(r.Name)
+	}
+	return json.Marshal(requiredEnvFields(r))
+}
+
+func (r *RequiredEnv) UnmarshalYAML(node *yaml.Node) error {
+	*r = RequiredEnv{}
+	if node.Kind == yaml.ScalarNode {
+		r.Name = node.Value
+		return nil
+	}
+	return decodeYAMLFields(node, (*requiredEnvFields)(r))
+}
+
+func (r RequiredEnv) MarshalYAML() (any, error) {
+	if r.Pattern == "" && r.Description == "" {
+		return r.Name, nil
+	}
+	return requiredEnvFields(r), nil
+}
+
+// MergeRequiredEnv appends the requirements of src to dst. A later entry for the same
+// variable replaces the earlier one in place.
+func MergeRequiredEnv(dst []RequiredEnv, src ...RequiredEnv) []RequiredEnv {
+	for _, r := range src {
+		replaced := false
+		for i := range dst {
+			if dst[i].Name == r.Name {
+				dst[i] = r
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst = append(dst, r)
+		}
+	}
+	return dst
+}
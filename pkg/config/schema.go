@@ -0,0 +1,268 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// CurrentSchema is the config schema version written by this version of mngproj
+const CurrentSchema = 3
+
+// Migration upgrades config files to its Version. The hooks receive files decoded
+// into generic maps and report whether they changed anything; they must be
+// idempotent, as files without their own schema marker may already be current.
+type Migration struct {
+	Version     int
+	Description string
+	Component   func(comp map[string]any) bool // Applied to components in project and local configs and to component fragments
+	Preset      func(preset map[string]any) bool
+
+	// The same changes made in place on TOML files, so that comments and layout survive
+	componentTOML func(doc *tomlDocument, comp *tomlSection)
+	presetTOML    func(doc *tomlDocument)
+}
+
+// Migrations lists the registered schema migrations in version order
+var Migrations = []Migration{
+	{
+		Version:     2,
+		Description: "component `type` is merged into `types`",
+		Component:   migrateTypeToTypes,
+
+		componentTOML: migrateTypeToTypesTOML,
+	},
+	{
+		Version:     3,
+		Description: "preset `gitignore` moves into `[metadata]`",
+		Preset:      migrateGitignoreToMetadata,
+
+		presetTOML: migrateGitignoreToMetadataTOML,
+	},
+}
+
+// FileKind is the layout of a config file passed to MigrateFile
+type FileKind int
+
+const (
+	ProjectFile  FileKind = iota // mngproj.toml: components list and the [project] schema
+	LocalFile                    // mngproj.local.*: components list
+	FragmentFile                 // component.*: a single component
+	PresetFile
+)
+
+// MigrateFile applies steps to the config file at path. It returns the descriptions
+// of the steps that changed it and the new contents, or nil when nothing changed.
+// With setSchema the project schema is set to CurrentSchema as well. TOML files are
+// edited in place, keeping comments and formatting; other formats are re-encoded.
+func MigrateFile(path string, kind FileKind, steps []Migration, setSchema bool) ([]string, []byte, error) {
+	doc, err := DecodeFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var applied []Migration
+	var descriptions []string
+	for _, mig := range steps {
+		if mig.apply(doc, kind) {
+			applied = append(applied, mig)
+			descriptions = append(descriptions, mig.Description)
+		}
+	}
+	if setSchema {
+		project, _ := doc["project"].(map[string]any)
+		if project == nil {
+			project = make(map[string]any)
+			doc["project"] = project
+		}
+		project["schema"] = CurrentSchema
+	}
+	if len(applied) == 0 && !setSchema {
+		return nil, nil, nil
+	}
+
+	if format, _ := FormatOf(path); format != FormatTOML {
+		data, err := encode(path, doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal %s: %w", path, err)
+		}
+		return descriptions, data, nil
+	}
+	data, err := migrateTOML(path, kind, applied, setSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The in-place edits must give the same result as the migrated document. Shapes
+	// they do not handle, such as inline tables of components, are left to the user.
+	var edited map[string]any
+	if err := decode(path, data, &edited); err != nil || !sameJSON(edited, doc) {
+		if setSchema {
+			descriptions = append(descriptions, fmt.Sprintf("`schema = %d` in [project]", CurrentSchema))
+		}
+		return nil, nil, fmt.Errorf("%s cannot be migrated in place; please apply these changes by hand: %s", path, strings.Join(descriptions, "; "))
+	}
+	return descriptions, data, nil
+}
+
+// apply runs the migration on a decoded file and reports whether it changed anything
+func (mig Migration) apply(doc map[string]any, kind FileKind) bool {
+	switch kind {
+	case PresetFile:
+		return mig.Preset != nil && mig.Preset(doc)
+	case FragmentFile:
+		return mig.Component != nil && mig.Component(doc)
+	}
+	if mig.Component == nil {
+		return false
+	}
+	changed := false
+	comps, _ := doc["components"].([]any)
+	for _, c := range comps {
+		if comp, ok := c.(map[string]any); ok && mig.Component(comp) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// migrateTOML edits the TOML file at path in place with the given migrations
+func migrateTOML(path string, kind FileKind, applied []Migration, setSchema bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseTOMLDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, mig := range applied {
+		switch {
+		case kind == PresetFile && mig.presetTOML != nil:
+			mig.presetTOML(doc)
+		case kind == FragmentFile && mig.componentTOML != nil:
+			mig.componentTOML(doc, doc.section())
+		case mig.componentTOML != nil:
+			for _, comp := range doc.arraySections("components") {
+				mig.componentTOML(doc, comp)
+			}
+		}
+	}
+	if setSchema {
+		if project := doc.section("project"); project == nil {
+			doc.insertTable("project", fmt.Sprintf("schema = %d\n", CurrentSchema))
+		} else if schema := project.get("schema"); schema != nil {
+			doc.replace(schema, "schema", CurrentSchema)
+		} else {
+			doc.insert(project, fmt.Sprintf("schema = %d\n", CurrentSchema))
+		}
+	}
+	return doc.bytes(), nil
+}
+
+// sameJSON reports whether a and b encode to the same JSON, ignoring numeric types
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// SchemaOf returns the effective schema version of a project config
+func SchemaOf(cfg *ProjectConfig) int {
+	return max(cfg.Project.Schema, 1)
+}
+
+// checkSchema rejects configs newer than this binary. Outdated ones still load; the
+// manager warns about them once per command.
+func checkSchema(path string, cfg *ProjectConfig) error {
+	schema := SchemaOf(cfg)
+	if schema > CurrentSchema {
+		return fmt.Errorf("%s uses config schema %d, but this mngproj only supports up to %d; please upgrade mngproj", path, schema, CurrentSchema)
+	}
+	return nil
+}
+
+func migrateTypeToTypes(comp map[string]any) bool {
+	typ, ok := comp["type"].(string)
+	if !ok {
+		return false
+	}
+	delete(comp, "type")
+	// `type` only took effect while `types` was empty
+	if types, _ := comp["types"].([]any); len(types) == 0 && typ != "" {
+		comp["types"] = []any{typ}
+	}
+	return true
+}
+
+func migrateGitignoreToMetadata(preset map[string]any) bool {
+	patterns, ok := preset["gitignore"].([]any)
+	if !ok {
+		return false
+	}
+	delete(preset, "gitignore")
+	meta, _ := preset["metadata"].(map[string]any)
+	if meta == nil {
+		meta = make(map[string]any)
+		preset["metadata"] = meta
+	}
+	existing, _ := meta["gitignore"].([]any)
+	for _, p := range patterns {
+		if !slices.Contains(existing, p) {
+			existing = append(existing, p)
+		}
+	}
+	meta["gitignore"] = existing
+	return true
+}
+
+func migrateTypeToTypesTOML(doc *tomlDocument, comp *tomlSection) {
+	typ := comp.get("type")
+	if typ == nil {
+		return
+	}
+	name, ok := typ.value.(string)
+	if !ok {
+		return
+	}
+	types := comp.get("types")
+	list, _ := types.valueList()
+	switch {
+	case len(list) > 0 || name == "":
+		// `type` only took effect while `types` was empty
+		doc.remove(typ)
+	case types != nil:
+		doc.replace(types, "types", []any{name})
+		doc.remove(typ)
+	default:
+		// Write types where type was, keeping its comment
+		doc.replace(typ, "types", []any{name})
+	}
+}
+
+func migrateGitignoreToMetadataTOML(doc *tomlDocument) {
+	root := doc.section()
+	legacy := root.get("gitignore")
+	if legacy == nil {
+		return
+	}
+	patterns, _ := legacy.valueList()
+	meta := doc.section("metadata")
+	switch {
+	case meta == nil:
+		doc.insertTable("metadata", doc.text(legacy))
+	case meta.get("gitignore") != nil:
+		existing := meta.get("gitignore")
+		merged, _ := existing.valueList()
+		for _, p := range patterns {
+			if !slices.Contains(merged, p) {
+				merged = append(merged, p)
+			}
+		}
+		doc.replace(existing, "gitignore", merged)
+	default:
+		// Move the entry as written, with its comments and layout
+		doc.insert(meta, doc.text(legacy))
+	}
+	doc.remove(legacy)
+}
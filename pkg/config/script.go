@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Script is a command run for a component. In config files it is either a plain
+// command string, an array of steps or a table with the fields below.
+type Script struct {
+	Cmd         string              `toml:"cmd,omitempty" json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	Steps       []string            `toml:"steps,omitempty" json:"steps,omitempty" yaml:"steps,omitempty"` // Commands run in order, stopping at the first failure
+	Cwd         string              `toml:"cwd,omitempty" json:"cwd,omitempty" yaml:"cwd,omitempty"`       // Working directory relative to the component path
+	Env         map[string]EnvValue `toml:"env,omitempty" json:"env,omitempty" yaml:"env,omitempty"`       // Applied on top of the component environment
+	Description string              `toml:"description,omitempty" json:"description,omitempty" yaml:"description,omitempty"`
+	Shell       string              `toml:"shell,omitempty" json:"shell,omitempty" yaml:"shell,omitempty"`       // e.g. "bash", "pwsh"; defaults to sh (powershell on Windows)
+	Timeout     string              `toml:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"` // Duration such as "30s" or "5m"
+	Retries     int                 `toml:"retries,omitempty" json:"retries,omitempty" yaml:"retries,omitempty"` // Extra attempts after a failure
+	When        string              `toml:"when,omitempty" json:"when,omitempty" yaml:"when,omitempty"`          // Condition for the script to exist, e.g. "os != 'windows'"
+}
+
+// scriptFields avoids recursing into the custom unmarshalers
+type scriptFields Script
+
+// Commands returns the command strings of the script in execution order
+func (s Script) Commands() []string {
+	if len(s.Steps) > 0 {
+		return s.Steps
+	}
+	return []string{s.Cmd}
+}
+
+// isPlain reports whether the script only carries a command or steps and can be written as a string or array
+func (s Script) isPlain() bool {
+	return s.Cwd == "" && s.Env == nil && s.Description == "" && s.Shell == "" && s.Timeout == "" && s.Retries == 0 && s.When == "" &&
+		(s.Cmd == "" || s.Steps == nil)
+}
+
+// UnmarshalTOML accepts `name = "cmd"`, arrays of steps and inline tables. Standard tables are decoded field by field.
+func (s *Script) UnmarshalTOML(node *unstable.Node) error {
+	return decodeNode(node, s)
+}
+
+func (s *Script) UnmarshalJSON(data []byte) error {
+	*s = Script{}
+	switch strings.TrimSpace(string(data))[0] {
+	case '"':
+		return json.Unmarshal(data, &s.Cmd)
+	case '[':
+		return json.Unmarshal(data, &s.Steps)
+	}
+	return decodeFields(data, (*scriptFields)(s))
+}
+
+func (s Script) MarshalJSON() ([]byte, error) {
+	switch {
+	case s.isPlain() && s.Steps != nil:
+		return json.Marshal(s.Steps)
+	case s.isPlain():
+		return json.Marshal(s.Cmd)
+	}
+	return json.Marshal(scriptFields(s))
+}
+
+func (s *Script) UnmarshalYAML(node *yaml.Node) error {
+	*s = Script{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		s.Cmd = node.Value
+		return nil
+	case yaml.SequenceNode:
+		return node.Decode(&s.Steps)
+	}
+	return decodeYAMLFields(node, (*scriptFields)(s))
+}
+
+func (s Script) MarshalYAML() (any, error) {
+	switch {
+	case s.isPlain() && s.Steps != nil:
+		return s.Steps, nil
+	case s.isPlain():
+		return s.Cmd, nil
+	}
+	return scriptFields(s), nil
+}
+
+// decodeFields decodes a JSON object into v, rejecting keys that v does not declare,
+// so that a misspelled field such as `whne` is an error instead of being ignored
+func decodeFields(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// decodeYAMLFields is decodeFields for YAML mappings
+func decodeYAMLFields(node *yaml.Node, v any) error {
+	if node.Kind == yaml.MappingNode {
+		known := make(map[string]bool)
+		t := reflect.TypeOf(v).Elem()
+		for i := range t.NumField() {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			known[name] = true
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i]; !known[key.Value] {
+				return fmt.Errorf("line %d: unknown field %q", key.Line, key.Value)
+			}
+		}
+	}
+	return node.Decode(v)
+}
+
+// decodeNode decodes a single TOML value into v by converting it to JSON, so that
+// types with a JSON representation can also be written as TOML inline values.
+func decodeNode(node *unstable.Node, v any) error {
+	value, err := nodeValue(node)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// nodeValue converts a TOML value node into plain Go values
+func nodeValue(node *unstable.Node) (any, error) {
+	switch node.Kind {
+	case unstable.String:
+		return string(node.Data), nil
+	case unstable.Bool:
+		return string(node.Data) == "true", nil
+	case unstable.Integer:
+		return strconv.ParseInt(strings.ReplaceAll(string(node.Data), "_", ""), 0, 64)
+	case unstable.Float:
+		return strconv.ParseFloat(strings.ReplaceAll(string(node.Data), "_", ""), 64)
+	case unstable.LocalDate, unstable.LocalTime, unstable.LocalDateTime, unstable.DateTime:
+		return string(node.Data), nil
+	case unstable.Array:
+		list := []any{}
+		it := node.Children()
+		for it.Next() {
+			// Comments only appear when the parser keeps them
+			if it.Node().Kind == unstable.Comment {
+				continue
+			}
+			item, err := nodeValue(it.Node())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case unstable.InlineTable:
+		table := make(map[string]any)
+		it := node.Children()
+		for it.Next() {
+			kv := it.Node()
+			var path []string
+			keys := kv.Key()
+			for keys.Next() {
+				path = append(path, string(keys.Node().Data))
+			}
+			value, err := nodeValue(kv.Value())
+			if err != nil {
+				return nil, err
+			}
+			// Dotted keys create nested tables
+			target := table
+			for _, k := range path[:len(path)-1] {
+				next, ok := target[k].(map[string]any)
+				if !ok {
+					next = make(map[string]any)
+					target[k] = next
+				}
+				target = next
+			}
+			target[path[len(path)-1]] = value
+		}
+		return table, nil
+	}
+	return nil, fmt.Errorf("unsupported TOML value %s", node.Kind)
+}
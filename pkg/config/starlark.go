@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// starlarkMaxSteps bounds the work a mngproj.star file may do
+const starlarkMaxSteps = 100_000_000
+
+// loadStarlarkConfig evaluates a mngproj.star file. The script declares the config
+// through builtins whose keyword arguments follow the TOML layout:
+//
+//	project(name = "shop", discovery = {"paths": ["libs/*"]})
+//	for d in glob("services/*"):
+//	    component(name = d.split("/")[-1], path = d, types = ["go"])
+//	preset("tool", metadata = {"role": "tool"}, scripts = {"lint": "tool lint"})
+//
+// glob and read_file only reach files below the directory holding the script, and
+// the json module is available for decoding data files. The script cannot run
+// commands or read the environment.
+func loadStarlarkConfig(path string) (*ProjectConfig, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	b := &starlarkBuilder{dir: filepath.Dir(path), cfg: &ProjectConfig{}}
+	predeclared := starlark.StringDict{
+		"project":   starlark.NewBuiltin("project", b.project),
+		"component": starlark.NewBuiltin("component", b.component),
+		"preset":    starlark.NewBuiltin("preset", b.preset),
+		"glob":      starlark.NewBuiltin("glob", b.glob),
+		"read_file": starlark.NewBuiltin("read_file", b.readFile),
+		"json":      starlarkjson.Module,
+	}
+	thread := &starlark.Thread{
+		Name:  path,
+		Print: func(_ *starlark.Thread, msg string) { fmt.Fprintln(os.Stderr, msg) },
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("load() is not supported in mngproj.star")
+		},
+	}
+	thread.SetMaxExecutionSteps(starlarkMaxSteps)
+	// Config files build lists in plain loops, so top-level control flow is allowed
+	opts := &syntax.FileOptions{TopLevelControl: true, GlobalReassign: true, Set: true}
+	if _, err := starlark.ExecFileOptions(opts, thread, path, src, predeclared); err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return nil, fmt.Errorf("failed to evaluate config file: %s", evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("failed to evaluate config file: %w", err)
+	}
+	// Generated configs are always current; migrations do not apply to them
+	if b.cfg.Project.Schema == 0 {
+		b.cfg.Project.Schema = CurrentSchema
+	}
+	return b.cfg, nil
+}
+
+// starlarkBuilder collects the declarations made by a mngproj.star file
+type starlarkBuilder struct {
+	dir string
+	cfg *ProjectConfig
+}
+
+func (b *starlarkBuilder) project(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	fields, err := kwargsToMap(fn, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	// discovery and resolution are top-level tables in TOML
+	top := make(map[string]any)
+	for _, key := range []string{"discovery", "resolution"} {
+		if v, ok := fields[key]; ok {
+			top[key] = v
+			delete(fields, key)
+		}
+	}
+	if err := decodeStrict(fields, &b.cfg.Project); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	if err := decodeStrict(top, b.cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	return starlark.None, nil
+}
+
+func (b *starlarkBuilder) component(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	fields, err := kwargsToMap(fn, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	var comp ComponentConfig
+	if err := decodeStrict(fields, &comp); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	if comp.Name == "" {
+		return nil, fmt.Errorf("%s: name is required", fn.Name())
+	}
+	b.cfg.Components = append(b.cfg.Components, comp)
+	return starlark.None, nil
+}
+
+func (b *starlarkBuilder) preset(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s: expected the preset name as the only positional argument", fn.Name())
+	}
+	name, ok := starlark.AsString(args[0])
+	if !ok || name == "" {
+		return nil, fmt.Errorf("%s: name must be a non-empty string", fn.Name())
+	}
+	fields, err := kwargsToMap(fn, nil, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	var preset PresetConfig
+	if err := decodeStrict(fields, &preset); err != nil {
+		return nil, fmt.Errorf("%s %q: %w", fn.Name(), name, err)
+	}
+	if preset.Metadata.Type == "" {
+		preset.Metadata.Type = name
+	}
+	if b.cfg.Presets == nil {
+		b.cfg.Presets = make(map[string]*PresetConfig)
+	}
+	if _, dup := b.cfg.Presets[name]; dup {
+		return nil, fmt.Errorf("%s: %q is defined twice", fn.Name(), name)
+	}
+	b.cfg.Presets[name] = &preset
+	return starlark.None, nil
+}
+
+// glob returns the sorted paths matching pattern, relative to the config directory
+func (b *starlarkBuilder) glob(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &pattern); err != nil {
+		return nil, err
+	}
+	if err := b.checkPath(pattern); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	matches, err := filepath.Glob(filepath.Join(b.dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	var list []starlark.Value
+	for _, match := range matches {
+		rel, err := filepath.Rel(b.dir, match)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, starlark.String(filepath.ToSlash(rel)))
+	}
+	return starlark.NewList(list), nil
+}
+
+// readFile returns the contents of a file relative to the config directory
+func (b *starlarkBuilder) readFile(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+	if err := b.checkPath(name); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	data, err := os.ReadFile(filepath.Join(b.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	return starlark.String(data), nil
+}
+
+// checkPath keeps file access below the config directory
+func (b *starlarkBuilder) checkPath(p string) error {
+	clean := filepath.Clean(filepath.FromSlash(p))
+	if filepath.IsAbs(clean) || clean == ".." || len(clean) > 2 && clean[:3] == ".."+string(filepath.Separator) {
+		return fmt.Errorf("path %q is outside the project", p)
+	}
+	return nil
+}
+
+// kwargsToMap converts keyword arguments to plain Go values; positional arguments are rejected
+func kwargsToMap(fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (map[string]any, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%s: only keyword arguments are accepted", fn.Name())
+	}
+	fields := make(map[string]any, len(kwargs))
+	for _, kv := range kwargs {
+		key := string(kv[0].(starlark.String))
+		v, err := fromStarlark(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", fn.Name(), key, err)
+		}
+		fields[key] = v
+	}
+	return fields, nil
+}
+
+// fromStarlark converts a Starlark value into the generic values produced by the config decoders
+func fromStarlark(v starlark.Value) (any, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, fmt.Errorf("integer %s is too large", v)
+		}
+		return i, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Indexable: // list and tuple
+		list := make([]any, v.Len())
+		for i := range list {
+			item, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case *starlark.Dict:
+		m := make(map[string]any, v.Len())
+		for _, item := range v.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings, got %s", item[0].Type())
+			}
+			value, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+}
+
+// decodeStrict decodes generic values into v through JSON, rejecting unknown fields
+func decodeStrict(fields any, v any) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// InlinePresetLayer serves presets defined in mngproj.star as a preset layer, so that
+// they are resolved exactly like preset files
+func InlinePresetLayer(name string, presets map[string]*PresetConfig) PresetLayer {
+	fsys := presetFS{}
+	for presetName, preset := range presets {
+		// The presets were decoded from JSON, so encoding them again cannot fail
+		data, _ := json.Marshal(preset)
+		fsys[presetName+".json"] = data
+	}
+	return PresetLayer{Name: name, FS: fsys}
+}
+
+// presetFS is a flat in-memory file system mapping file names to their contents
+type presetFS map[string][]byte
+
+func (f presetFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &presetFile{info: presetFileInfo{name: ".", dir: true}}, nil
+	}
+	data, ok := f[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &presetFile{info: presetFileInfo{name: name, size: int64(len(data))}, r: bytes.NewReader(data)}, nil
+}
+
+func (f presetFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(f))
+	for fileName, data := range f {
+		entries = append(entries, fs.FileInfoToDirEntry(presetFileInfo{name: fileName, size: int64(len(data))}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type presetFile struct {
+	info presetFileInfo
+	r    *bytes.Reader
+}
+
+func (f *presetFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *presetFile) Close() error               { return nil }
+
+func (f *presetFile) Read(p []byte) (int, error) {
+	if f.r == nil {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	return f.r.Read(p)
+}
+
+type presetFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i presetFileInfo) Name() string       { return i.name }
+func (i presetFileInfo) Size() int64        { return i.size }
+func (i presetFileInfo) ModTime() time.Time { return time.Time{} }
+func (i presetFileInfo) IsDir() bool        { return i.dir }
+func (i presetFileInfo) Sys() any           { return nil }
+
+func (i presetFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// tomlDocument edits a TOML file in place. Only the lines of changed keys are
+// rewritten, so comments, quoting and the layout of everything else survive.
+type tomlDocument struct {
+	data     []byte
+	sections []*tomlSection // The root table first, then one per header in file order
+	edits    []tomlEdit
+}
+
+// tomlSection is a table header and the keys written below it
+type tomlSection struct {
+	header    []string // nil for the root table
+	array     bool     // [[header]]
+	start     int      // Start of the header line; 0 for the root table
+	headerEnd int      // End of the header line; 0 for the root table
+	entries   []*tomlEntry
+}
+
+// tomlEntry is a key/value line, or lines for multi-line values
+type tomlEntry struct {
+	key        []string
+	value      any    // Decoded value
+	start, end int    // Byte span from the start of the first line to the end of the last one
+	indent     string // Whitespace before the key
+	comment    string // Trailing comment, e.g. "# legacy"
+}
+
+type tomlEdit struct {
+	start, end int
+	text       string
+}
+
+// parseTOMLDocument indexes the top-level expressions of data with their line spans
+func parseTOMLDocument(data []byte) (*tomlDocument, error) {
+	doc := &tomlDocument{data: data, sections: []*tomlSection{{}}}
+	type expr struct {
+		start   int
+		entry   *tomlEntry
+		section *tomlSection
+	}
+	var exprs []expr
+
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(data)
+	for p.NextExpression() {
+		node := p.Expression()
+		if node.Kind == unstable.Comment {
+			exprs = append(exprs, expr{start: lineStart(data, int(node.Raw.Offset))})
+			continue
+		}
+		var key []string
+		first := -1
+		it := node.Key()
+		for it.Next() {
+			k := it.Node()
+			if first < 0 {
+				first = int(k.Raw.Offset)
+			}
+			key = append(key, string(k.Data))
+		}
+		e := expr{start: lineStart(data, first)}
+		switch node.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			e.section = &tomlSection{header: key, array: node.Kind == unstable.ArrayTable, start: e.start}
+			doc.sections = append(doc.sections, e.section)
+		case unstable.KeyValue:
+			value, err := nodeValue(node.Value())
+			if err != nil {
+				return nil, err
+			}
+			e.entry = &tomlEntry{key: key, value: value, indent: string(data[e.start:first])}
+			if c := node.Next(); c != nil && c.Kind == unstable.Comment {
+				e.entry.comment = string(c.Data)
+			}
+			current := doc.sections[len(doc.sections)-1]
+			current.entries = append(current.entries, e.entry)
+		}
+		exprs = append(exprs, e)
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	// An expression ends where the next one starts, minus the blank lines in between
+	for i, e := range exprs {
+		end := len(data)
+		if i+1 < len(exprs) {
+			end = exprs[i+1].start
+		}
+		end = trimBlankLines(data, e.start, end)
+		if e.entry != nil {
+			e.entry.start, e.entry.end = e.start, end
+		}
+		if e.section != nil {
+			e.section.headerEnd = end
+		}
+	}
+	return doc, nil
+}
+
+// lineStart returns the offset of the start of the line containing offset
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// trimBlankLines moves end back over whitespace-only lines, but not before start
+func trimBlankLines(data []byte, start, end int) int {
+	for end > start {
+		ls := lineStart(data, end-1)
+		if ls < start || len(bytes.TrimSpace(data[ls:end])) > 0 {
+			break
+		}
+		end = ls
+	}
+	return end
+}
+
+// section returns the first non-array table with the given header
+func (d *tomlDocument) section(header ...string) *tomlSection {
+	for _, s := range d.sections {
+		if !s.array && slices.Equal(s.header, header) && (s.header != nil || len(header) == 0) {
+			return s
+		}
+	}
+	return nil
+}
+
+// arraySections returns every [[header]] element
+func (d *tomlDocument) arraySections(header ...string) []*tomlSection {
+	var list []*tomlSection
+	for _, s := range d.sections {
+		if s.array && slices.Equal(s.header, header) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// get returns the entry for key written directly in the section
+func (s *tomlSection) get(key string) *tomlEntry {
+	for _, e := range s.entries {
+		if len(e.key) == 1 && e.key[0] == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// valueList returns the value of e when it is an array; e may be nil
+func (e *tomlEntry) valueList() ([]any, bool) {
+	if e == nil {
+		return nil, false
+	}
+	list, ok := e.value.([]any)
+	return slices.Clone(list), ok
+}
+
+// remove deletes the lines of e
+func (d *tomlDocument) remove(e *tomlEntry) {
+	d.edits = append(d.edits, tomlEdit{start: e.start, end: e.end})
+}
+
+// replace rewrites e as key = value, keeping its indentation and trailing comment
+func (d *tomlDocument) replace(e *tomlEntry, key string, value any) {
+	line := e.indent + key + " = " + formatTOMLValue(value)
+	if e.comment != "" {
+		line += " " + e.comment
+	}
+	if e.end > 0 && d.data[e.end-1] == '\n' {
+		line += "\n"
+	}
+	d.edits = append(d.edits, tomlEdit{start: e.start, end: e.end, text: line})
+}
+
+// insert adds text, which must end with a newline, after the last key of s
+func (d *tomlDocument) insert(s *tomlSection, text string) {
+	at := s.headerEnd
+	if len(s.entries) > 0 {
+		at = s.entries[len(s.entries)-1].end
+	}
+	if at > 0 && d.data[at-1] != '\n' {
+		text = "\n" + text
+	}
+	d.edits = append(d.edits, tomlEdit{start: at, end: at, text: text})
+}
+
+// insertTable adds a new table with the given lines before the first header, or at
+// the end of a document without tables
+func (d *tomlDocument) insertTable(header string, lines ...string) {
+	text := "[" + header + "]\n" + strings.Join(lines, "")
+	if len(d.sections) > 1 {
+		at := d.sections[1].start
+		d.edits = append(d.edits, tomlEdit{start: at, end: at, text: text + "\n"})
+		return
+	}
+	at := len(d.data)
+	if at > 0 && d.data[at-1] != '\n' {
+		text = "\n" + text
+	}
+	if at > 0 {
+		text = "\n" + text
+	}
+	d.edits = append(d.edits, tomlEdit{start: at, end: at, text: text})
+}
+
+// text returns the lines of e, ending with a newline
+func (d *tomlDocument) text(e *tomlEntry) string {
+	text := string(d.data[e.start:e.end])
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
+// bytes returns the document with all edits applied
+func (d *tomlDocument) bytes() []byte {
+	edits := slices.Clone(d.edits)
+	// Stable, so that inserts at the same offset keep their order
+	slices.SortStableFunc(edits, func(a, b tomlEdit) int { return a.start - b.start })
+	var out bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		if e.start > pos {
+			out.Write(d.data[pos:e.start])
+		}
+		out.WriteString(e.text)
+		pos = max(pos, e.end)
+	}
+	out.Write(d.data[pos:])
+	return out.Bytes()
+}
+
+// formatTOMLValue renders strings, integers and arrays of them as TOML
+func formatTOMLValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatTOMLValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
@@ -2,49 +2,102 @@ package config
 
 // ProjectConfig represents the root mngproj.toml
 type ProjectConfig struct {
-	Project    ProjectMeta       `toml:"project"`
-	Components []ComponentConfig `toml:"components"`
-	Resolution ResolutionConfig  `toml:"resolution"`
+	Project    ProjectMeta       `toml:"project" json:"project,omitempty" yaml:"project,omitempty"`
+	Components []ComponentConfig `toml:"components" json:"components,omitempty" yaml:"components,omitempty"`
+	Resolution ResolutionConfig  `toml:"resolution" json:"resolution,omitempty" yaml:"resolution,omitempty"`
+	Discovery  DiscoveryConfig   `toml:"discovery" json:"discovery,omitempty" yaml:"discovery,omitempty"`
+
+	// Presets defined inline by mngproj.star, searched before all preset directories
+	Presets map[string]*PresetConfig `toml:"-" json:"-" yaml:"-"`
+}
+
+// DiscoveryConfig lists directories that contribute components automatically
+type DiscoveryConfig struct {
+	// Glob patterns relative to the project root, e.g. "services/*"
+	Paths []string `toml:"paths" json:"paths,omitempty" yaml:"paths,omitempty"`
 }
 
 type ResolutionConfig struct {
 	// Map of role name to priority score. Higher wins.
-	RolePriority map[string]int `toml:"role_priority"`
+	RolePriority map[string]int `toml:"role_priority" json:"role_priority,omitempty" yaml:"role_priority,omitempty"`
+	// Map of script name to the preset that provides it, regardless of role priority, e.g. {run = "uv"}
+	Scripts map[string]string `toml:"scripts" json:"scripts,omitempty" yaml:"scripts,omitempty"`
 }
 
 // ProjectMeta contains metadata about the project
 type ProjectMeta struct {
-	Name        string   `toml:"name"`
-	Description string   `toml:"description"`
-	Tags        []string `toml:"tags"`
-	Root        string   `toml:"root"` // Optional: explicit project root
+	Name        string   `toml:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	Description string   `toml:"description" json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string `toml:"tags" json:"tags,omitempty" yaml:"tags,omitempty"`
+	Schema      int      `toml:"schema,omitempty" json:"schema,omitempty" yaml:"schema,omitempty"` // Config schema version; missing means 1
+	Root        string   `toml:"root" json:"root,omitempty" yaml:"root,omitempty"`                 // Optional: explicit project root
+	EnvFiles    []string `toml:"env_files" json:"env_files,omitempty" yaml:"env_files,omitempty"`  // Dotenv files relative to the project root, loaded for every component
+
+	Scripts map[string]Script   `toml:"scripts" json:"scripts,omitempty" yaml:"scripts,omitempty"` // Repo-wide tasks run in the project root, e.g. `mngproj bootstrap`
+	Env     map[string]EnvValue `toml:"env" json:"env,omitempty" yaml:"env,omitempty"`             // Environment of the project scripts
 }
 
 // ComponentConfig represents a component definition in mngproj.toml
 type ComponentConfig struct {
-	Name         string            `toml:"name"`
-	Type         string            `toml:"type"`
-	Types        []string          `toml:"types"`
-	Path         string            `toml:"path"`
-	Priority     int               `toml:"priority"`
-	Groups       []string          `toml:"groups"`
-	Dependencies []string          `toml:"dependencies"`
-	Env          map[string]string `toml:"env"`
-	Scripts      map[string]string `toml:"scripts"`
+	Name         string              `toml:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	Type         string              `toml:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Types        []string            `toml:"types" json:"types,omitempty" yaml:"types,omitempty"`
+	Path         string              `toml:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	Priority     int                 `toml:"priority" json:"priority,omitempty" yaml:"priority,omitempty"`
+	Groups       []string            `toml:"groups" json:"groups,omitempty" yaml:"groups,omitempty"`
+	Dependencies []string            `toml:"dependencies" json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Env          map[string]EnvValue `toml:"env" json:"env,omitempty" yaml:"env,omitempty"`
+	Scripts      map[string]Script   `toml:"scripts" json:"scripts,omitempty" yaml:"scripts,omitempty"`
+	Params       map[string]any      `toml:"params" json:"params,omitempty" yaml:"params,omitempty"`                       // Overrides for parameters declared by presets
+	EnvFiles     []string            `toml:"env_files" json:"env_files,omitempty" yaml:"env_files,omitempty"`              // Dotenv files relative to the component path
+	Ports        map[string]any      `toml:"ports" json:"ports,omitempty" yaml:"ports,omitempty"`                          // Named ports: a number or "auto", e.g. {http = 8000}
+	RequiredEnv  []RequiredEnv       `toml:"required_env" json:"required_env,omitempty" yaml:"required_env,omitempty"`     // Variables checked before any script starts
+	Resolution   *ResolutionConfig   `toml:"resolution,omitempty" json:"resolution,omitempty" yaml:"resolution,omitempty"` // Role priorities and script pins for this component, over the project's
+
+	Disabled  bool       `toml:"disabled" json:"disabled,omitempty" yaml:"disabled,omitempty"`    // Excludes the component, typically set in mngproj.local.toml
+	When      string     `toml:"when" json:"when,omitempty" yaml:"when,omitempty"`                // Condition for the component to exist, e.g. "arch == 'arm64'"
+	Overrides []Override `toml:"overrides" json:"overrides,omitempty" yaml:"overrides,omitempty"` // Conditional scripts and env, applied after the component's own
+
+	// Origin is the component fragment this component was discovered from; empty for declared components
+	Origin string `toml:"-" json:"-" yaml:"-"`
 }
 
 // PresetConfig represents a preset definition (e.g. presets/go.toml)
 type PresetConfig struct {
-	Metadata PresetMeta        `toml:"metadata"`
-	Scripts  map[string]string `toml:"scripts"`
-	Env      map[string]string `toml:"env"`
-	Gitignore []string         `toml:"gitignore"`
+	Extends   []string            `toml:"extends" json:"extends,omitempty" yaml:"extends,omitempty"` // Parent presets, applied before this one
+	Metadata  PresetMeta          `toml:"metadata" json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Scripts   map[string]Script   `toml:"scripts" json:"scripts,omitempty" yaml:"scripts,omitempty"`
+	Env       map[string]EnvValue `toml:"env" json:"env,omitempty" yaml:"env,omitempty"`
+	Gitignore []string            `toml:"gitignore" json:"gitignore,omitempty" yaml:"gitignore,omitempty"` // Legacy location (schema < 3), folded into Metadata.Gitignore when loaded
+	Params    map[string]any      `toml:"params" json:"params,omitempty" yaml:"params,omitempty"`          // Declared parameters with their defaults; the default fixes the type
+	Overrides []Override          `toml:"overrides" json:"overrides,omitempty" yaml:"overrides,omitempty"` // Conditional scripts and env, applied after the preset's own
+
+	RequiredEnv []RequiredEnv `toml:"required_env" json:"required_env,omitempty" yaml:"required_env,omitempty"` // Variables every component using the preset needs
+}
+
+// Override carries scripts and env entries that only apply when its condition holds
+type Override struct {
+	When    string              `toml:"when" json:"when,omitempty" yaml:"when,omitempty"` // e.g. "os == 'linux' && env.CI != 'true'"
+	Scripts map[string]Script   `toml:"scripts" json:"scripts,omitempty" yaml:"scripts,omitempty"`
+	Env     map[string]EnvValue `toml:"env" json:"env,omitempty" yaml:"env,omitempty"`
 }
 
 type PresetMeta struct {
-	Type          string   `toml:"type"`
-	Role          string   `toml:"role"` // language, framework, package_manager, tool
-	Description   string   `toml:"description"`
-	ManifestFile  string   `toml:"manifest_file"` // e.g. "requirements.txt", "package.json"
-	RequiredTools []string `toml:"required_tools"` // e.g. ["go", "docker"]
+	Type          string         `toml:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Role          string         `toml:"role" json:"role,omitempty" yaml:"role,omitempty"`    // language, framework, package_manager, tool
+	Roles         map[string]int `toml:"roles" json:"roles,omitempty" yaml:"roles,omitempty"` // New roles with their default priority, e.g. {container = 25}
+	Description   string         `toml:"description" json:"description,omitempty" yaml:"description,omitempty"`
+	ManifestFile  string         `toml:"manifest_file" json:"manifest_file,omitempty" yaml:"manifest_file,omitempty"`    // e.g. "requirements.txt", "package.json"
+	RequiredTools []string       `toml:"required_tools" json:"required_tools,omitempty" yaml:"required_tools,omitempty"` // e.g. ["go", "docker"]
+	Requires      []string       `toml:"requires" json:"requires,omitempty" yaml:"requires,omitempty"`                   // Presets auto-included when missing, e.g. ["node"]
+	Conflicts     []string       `toml:"conflicts" json:"conflicts,omitempty" yaml:"conflicts,omitempty"`                // Presets that cannot be combined with this one
+	Detect        DetectRules    `toml:"detect" json:"detect,omitempty" yaml:"detect,omitempty"`                         // How to recognise a directory using this preset; not inherited
+	Gitignore     []string       `toml:"gitignore" json:"gitignore,omitempty" yaml:"gitignore,omitempty"`                // Patterns `init` adds to .gitignore
+}
+
+// DetectRules describe the files that identify a preset. A directory matches when
+// any file pattern exists or any listed JSON key is present.
+type DetectRules struct {
+	Files []string            `toml:"files" json:"files,omitempty" yaml:"files,omitempty"` // Glob patterns, e.g. ["go.mod"]
+	JSON  map[string][]string `toml:"json" json:"json,omitempty" yaml:"json,omitempty"`    // JSON file -> dotted keys, e.g. {"package.json" = ["dependencies.next"]}
 }
@@ -0,0 +1,305 @@
+// Package expr implements the small condition language used by `when` fields.
+//
+// Grammar:
+//
+//	expr    = or
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = primary [ ( "==" | "!=" | "in" ) primary ]
+//	primary = "(" expr ")" | string | "true" | "false" | ident { "." ident }
+//
+// Strings use single or double quotes. Values are strings, booleans or string
+// lists; `x in list` tests membership and `x in "text"` tests for a substring.
+// Identifiers are resolved through Vars, except env.NAME which reads Env.
+// Expressions cannot call functions or modify anything.
+package expr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Context supplies the values visible to an expression
+type Context struct {
+	Vars map[string]any           // Dotted names such as "os" or "component.types"; string, bool or []string
+	Env  func(name string) string // Lookup for env.NAME; unset variables read as ""
+}
+
+// Eval parses and evaluates src, which must produce a boolean-like value.
+// An empty expression is true.
+func Eval(src string, ctx Context) (bool, error) {
+	if strings.TrimSpace(src) == "" {
+		return true, nil
+	}
+	p := &parser{src: src, ctx: ctx}
+	if err := p.next(); err != nil {
+		return false, p.errorf("%v", err)
+	}
+	v, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.tok.kind != tokEOF {
+		return false, p.errorf("unexpected %q", p.tok.text)
+	}
+	return truthy(v), nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+	ctx Context
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid expression %q at offset %d: %s", p.src, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+// next advances to the next token
+func (p *parser) next() error {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], c)
+		if end < 0 {
+			p.tok = token{pos: start}
+			return fmt.Errorf("unterminated string")
+		}
+		p.tok = token{kind: tokString, text: p.src[p.pos+1 : p.pos+1+end], pos: start}
+		p.pos += end + 2
+		return nil
+	case isIdentChar(c):
+		for p.pos < len(p.src) && (isIdentChar(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+		return nil
+	}
+
+	for _, op := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			p.tok = token{kind: tokOp, text: op, pos: start}
+			return nil
+		}
+	}
+	p.tok = token{pos: start}
+	return fmt.Errorf("unexpected character %q", c)
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) advance() error {
+	if err := p.next(); err != nil {
+		return p.errorf("%v", err)
+	}
+	return nil
+}
+
+// Both sides of && and || are always evaluated so that errors such as unknown
+// identifiers are reported regardless of the current machine.
+func (p *parser) parseOr() (any, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) || truthy(right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (any, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) && truthy(right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (any, error) {
+	if p.isOp("!") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(v), nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (any, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch {
+	case p.isOp("=="), p.isOp("!="):
+		op = p.tok.text
+	case p.tok.kind == tokIdent && p.tok.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+	switch r := right.(type) {
+	case []string:
+		return slices.Contains(r, toString(left)), nil
+	case string:
+		return strings.Contains(r, toString(left)), nil
+	}
+	return nil, p.errorf("right side of 'in' must be a list or string")
+}
+
+func (p *parser) parsePrimary() (any, error) {
+	tok := p.tok
+	switch {
+	case p.isOp("("):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return v, p.advance()
+	case tok.kind == tokString:
+		return tok.text, p.advance()
+	case tok.kind == tokIdent:
+		v, err := p.lookup(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		return v, p.advance()
+	case tok.kind == tokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) lookup(name string) (any, error) {
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if key, ok := strings.CutPrefix(name, "env."); ok {
+		if p.ctx.Env == nil {
+			return "", nil
+		}
+		return p.ctx.Env(key), nil
+	}
+	if v, ok := p.ctx.Vars[name]; ok {
+		return v, nil
+	}
+	return nil, p.errorf("unknown identifier %q", name)
+}
+
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	if ab, ok := a.(bool); ok {
+		return ab == truthy(b)
+	}
+	if bb, ok := b.(bool); ok {
+		return bb == truthy(a)
+	}
+	return toString(a) == toString(b)
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return ""
+}
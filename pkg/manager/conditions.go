@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"fmt"
+	"maps"
+	"mngproj/pkg/config"
+	"mngproj/pkg/expr"
+	"os"
+	"runtime"
+)
+
+// ProfileEnvVar selects the active profile when --profile is not given
+const ProfileEnvVar = "MNGPROJ_PROFILE"
+
+// SetProfile changes the active profile and re-evaluates the components' `when` conditions
+func (m *Manager) SetProfile(profile string) error {
+	m.Profile = profile
+	return m.filterComponents()
+}
+
+// filterComponents keeps the components whose `when` condition holds and that are not disabled. The full list is
+// remembered so that a later profile change can bring filtered components back.
+func (m *Manager) filterComponents() error {
+	if m.allComponents == nil {
+		m.allComponents = m.ProjectConfig.Components
+	}
+
+	var active []config.ComponentConfig
+	for i := range m.allComponents {
+		comp := &m.allComponents[i]
+		if comp.Disabled {
+			continue
+		}
+		if comp.When == "" {
+			active = append(active, *comp)
+			continue
+		}
+		types, err := m.EffectiveTypes(comp)
+		if err != nil {
+			return err
+		}
+		ok, err := m.evalWhen(comp.When, comp, types)
+		if err != nil {
+			return fmt.Errorf("component %q: %w", comp.Name, err)
+		}
+		if ok {
+			active = append(active, *comp)
+		}
+	}
+	m.ProjectConfig.Components = active
+	return nil
+}
+
+// evalWhen evaluates a condition for comp, whose effective preset types are given
+func (m *Manager) evalWhen(when string, comp *config.ComponentConfig, types []string) (bool, error) {
+	if types == nil {
+		types = []string{}
+	}
+	return expr.Eval(when, expr.Context{
+		Vars: map[string]any{
+			"os":              runtime.GOOS,
+			"arch":            runtime.GOARCH,
+			"profile":         m.Profile,
+			"component.name":  comp.Name,
+			"component.path":  comp.Path,
+			"component.types": types,
+		},
+		Env: func(name string) string {
+			if v, ok := m.ExtraEnv[name]; ok {
+				return v
+			}
+			return os.Getenv(name)
+		},
+	})
+}
+
+// matchingOverrides returns the overrides whose condition holds, in declaration order
+func (m *Manager) matchingOverrides(overrides []config.Override, comp *config.ComponentConfig, types []string) ([]config.Override, error) {
+	var matched []config.Override
+	for _, o := range overrides {
+		ok, err := m.evalWhen(o.When, comp, types)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, o)
+		}
+	}
+	return matched, nil
+}
+
+// activeScripts returns the scripts whose `when` condition holds, with the env
+// entries of each script filtered the same way
+func (m *Manager) activeScripts(scripts map[string]config.Script, comp *config.ComponentConfig, types []string) (map[string]config.Script, error) {
+	active := make(map[string]config.Script, len(scripts))
+	for name, script := range scripts {
+		ok, err := m.evalWhen(script.When, comp, types)
+		if err != nil {
+			return nil, fmt.Errorf("script %q: %w", name, err)
+		}
+		if !ok {
+			continue
+		}
+		if script.Env, err = m.activeEnv(script.Env, comp, types); err != nil {
+			return nil, fmt.Errorf("script %q: %w", name, err)
+		}
+		active[name] = script
+	}
+	return active, nil
+}
+
+// activeEnv returns the env entries whose `when` condition holds. A nil env stays nil.
+func (m *Manager) activeEnv(env map[string]config.EnvValue, comp *config.ComponentConfig, types []string) (map[string]config.EnvValue, error) {
+	if env == nil {
+		return nil, nil
+	}
+	active := make(map[string]config.EnvValue, len(env))
+	for k, v := range env {
+		ok, err := m.evalWhen(v.When, comp, types)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+		if ok {
+			active[k] = v
+		}
+	}
+	return active, nil
+}
+
+// activeConfig returns the scripts and env of a preset or component whose `when`
+// conditions hold, with the matching overrides applied on top
+func (m *Manager) activeConfig(scripts map[string]config.Script, env map[string]config.EnvValue, overrides []config.Override, comp *config.ComponentConfig, types []string) (map[string]config.Script, map[string]config.EnvValue, error) {
+	activeScripts, err := m.activeScripts(scripts, comp, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	activeEnv, err := m.activeEnv(env, comp, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	if activeEnv == nil {
+		activeEnv = make(map[string]config.EnvValue)
+	}
+	matched, err := m.matchingOverrides(overrides, comp, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, o := range matched {
+		overrideScripts, err := m.activeScripts(o.Scripts, comp, types)
+		if err != nil {
+			return nil, nil, err
+		}
+		overrideEnv, err := m.activeEnv(o.Env, comp, types)
+		if err != nil {
+			return nil, nil, err
+		}
+		maps.Copy(activeScripts, overrideScripts)
+		config.MergeEnv(activeEnv, overrideEnv)
+	}
+	return activeScripts, activeEnv, nil
+}
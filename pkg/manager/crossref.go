@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ComponentView is the data returned by the `component` template function
+type ComponentView struct {
+	Name   string
+	Path   string // Absolute component directory
+	Types  []string
+	Env    map[string]string // Fully resolved environment, as seen by the component's scripts
+	Params map[string]any
+}
+
+// componentView resolves another component for a reference made while computing the
+// env of the components in stack
+func (m *Manager) componentView(name string, stack []string) (*ComponentView, error) {
+	if slices.Contains(stack, name) {
+		return nil, fmt.Errorf("component reference cycle: %s -> %s", strings.Join(stack, " -> "), name)
+	}
+	comp, err := m.ResolveComponent(name)
+	if err != nil {
+		return nil, err
+	}
+	env, err := m.buildEnv(comp, nil, append(slices.Clone(stack), name))
+	if err != nil {
+		return nil, err
+	}
+	return &ComponentView{Name: comp.Name, Path: comp.AbsPath, Types: comp.Types, Env: env, Params: comp.Params}, nil
+}
+
+// componentVar returns the value of a ${components.REF} reference, where REF is
+// NAME.env.VAR or NAME.path
+func (m *Manager) componentVar(ref string, stack []string) (string, error) {
+	name, field, ok := strings.Cut(ref, ".")
+	if !ok {
+		return "", fmt.Errorf("invalid component reference ${components.%s}", ref)
+	}
+	view, err := m.componentView(name, stack)
+	if err != nil {
+		return "", err
+	}
+	if field == "path" {
+		return view.Path, nil
+	}
+	if key, ok := strings.CutPrefix(field, "env."); ok {
+		value, ok := view.Env[key]
+		if !ok {
+			return "", fmt.Errorf("component %q has no env %s", name, key)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid component reference ${components.%s}, expected env.VAR or path", ref)
+}
@@ -0,0 +1,249 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"mngproj/pkg/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// detectSkipDirs are never scanned for components
+var detectSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+	"venv":         true,
+	"__pycache__":  true,
+}
+
+// Detector matches directories against the detection rules declared by presets
+type Detector struct {
+	names   []string // Preset names ordered by role score, then name
+	presets map[string]*config.PresetConfig
+}
+
+// NewDetector loads every preset of the registry that declares detection rules.
+// Presets that fail to load are skipped with a warning to warnings (nil discards it),
+// so that one broken preset does not stop detection; components that use it still report the error.
+// Types are reported in ascending role order (languages first, frameworks last),
+// using rolePriority to override the default role scores.
+func NewDetector(reg *config.PresetRegistry, rolePriority map[string]int, warnings io.Writer) (*Detector, error) {
+	infos, err := reg.List()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Detector{presets: make(map[string]*config.PresetConfig)}
+	// Roles declared by any preset, overridden by the project's priorities
+	priorities := make(map[string]int)
+	for _, info := range infos {
+		preset, err := reg.Load(info.Name)
+		if err != nil {
+			if warnings != nil {
+				fmt.Fprintf(warnings, "Warning: skipping preset %q for detection: %v\n", info.Name, err)
+			}
+			continue
+		}
+		maps.Copy(priorities, preset.Metadata.Roles)
+		// {type}_{GOOS} variants are detected through their base type
+		if preset.Metadata.Type != "" && preset.Metadata.Type != info.Name {
+			continue
+		}
+		rules := preset.Metadata.Detect
+		if len(rules.Files) == 0 && len(rules.JSON) == 0 {
+			continue
+		}
+		d.names = append(d.names, info.Name)
+		d.presets[info.Name] = preset
+	}
+
+	maps.Copy(priorities, rolePriority)
+
+	sort.SliceStable(d.names, func(i, j int) bool {
+		si := roleScore(priorities, d.presets[d.names[i]].Metadata.Role)
+		sj := roleScore(priorities, d.presets[d.names[j]].Metadata.Role)
+		if si != sj {
+			return si < sj
+		}
+		return d.names[i] < d.names[j]
+	})
+	return d, nil
+}
+
+// Types returns the preset types whose detection rules match dir
+func (d *Detector) Types(dir string) ([]string, error) {
+	var types []string
+	for _, name := range d.names {
+		ok, err := matchRules(dir, d.presets[name].Metadata.Detect)
+		if err != nil {
+			return nil, fmt.Errorf("detection of %q failed: %w", name, err)
+		}
+		if ok {
+			types = append(types, name)
+		}
+	}
+	return types, nil
+}
+
+// Scan walks the tree under root and proposes a component for every directory with
+// detected types. Component paths are relative to root and names default to the directory name.
+func (d *Detector) Scan(root string) ([]config.ComponentConfig, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var comps []config.ComponentConfig
+	names := make(map[string]bool)
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(entry.Name(), ".") || detectSkipDirs[entry.Name()]) {
+			return filepath.SkipDir
+		}
+
+		types, err := d.Types(path)
+		if err != nil || len(types) == 0 {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		name := filepath.Base(path)
+		if names[name] {
+			name = strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+		}
+		names[name] = true
+		comps = append(comps, config.ComponentConfig{Name: name, Types: types, Path: filepath.ToSlash(rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %q: %w", root, err)
+	}
+	return comps, nil
+}
+
+func matchRules(dir string, rules config.DetectRules) (bool, error) {
+	for _, pattern := range rules.Files {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return false, fmt.Errorf("invalid detect pattern %q: %w", pattern, err)
+		}
+		if len(matches) > 0 {
+			return true, nil
+		}
+	}
+
+	for file, keys := range rules.JSON {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			// Broken JSON in a scanned project is not a reason to abort detection
+			continue
+		}
+		for _, key := range keys {
+			if hasJSONKey(doc, key) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// hasJSONKey reports whether the dotted key path exists in doc
+func hasJSONKey(doc any, key string) bool {
+	for _, part := range strings.Split(key, ".") {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return false
+		}
+		if doc, ok = obj[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteDetectedComponents adds the proposed components found under root to the project
+// config that owns root, creating mngproj.toml in root when there is none. Components whose
+// directory is already used by the project are skipped. It returns the names added.
+func WriteDetectedComponents(root string, comps []config.ComponentConfig) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath, err := FindConfigFile(root)
+	if errors.Is(err, ErrConfigNotFound) {
+		if err := writeNewConfig(filepath.Join(root, "mngproj.toml"), filepath.Base(root), comps); err != nil {
+			return nil, err
+		}
+		var added []string
+		for _, c := range comps {
+			added = append(added, c.Name)
+		}
+		return added, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := New(root)
+	if err != nil {
+		return nil, err
+	}
+	usedPaths := make(map[string]bool)
+	usedNames := make(map[string]bool)
+	for _, c := range m.ProjectConfig.Components {
+		usedPaths[filepath.Join(m.ProjectDir, c.Path)] = true
+		usedNames[c.Name] = true
+	}
+
+	// Re-read the config so discovered components are not written back
+	cfg, err := config.LoadProjectConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, c := range comps {
+		abs := filepath.Join(root, filepath.FromSlash(c.Path))
+		if usedPaths[abs] {
+			continue
+		}
+		rel, err := filepath.Rel(m.ProjectDir, abs)
+		if err != nil {
+			return nil, err
+		}
+		c.Path = filepath.ToSlash(rel)
+		if usedNames[c.Name] {
+			c.Name = strings.ReplaceAll(c.Path, "/", "-")
+			if usedNames[c.Name] {
+				continue
+			}
+		}
+		usedNames[c.Name] = true
+		cfg.Components = append(cfg.Components, c)
+		added = append(added, c.Name)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := config.SaveProjectConfig(configPath, cfg); err != nil {
+		return nil, fmt.Errorf("failed to save project config: %w", err)
+	}
+	return added, nil
+}
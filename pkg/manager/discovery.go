@@ -1,30 +1,63 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"mngproj/pkg/config"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// FindConfigFile looks for mngproj.toml starting from startDir and walking up
+// ErrConfigNotFound is returned when no project config exists in a directory or its parents
+var ErrConfigNotFound = errors.New("mngproj.toml not found")
+
+// FindConfigFile looks for mngproj.toml (or .yaml/.yml/.json) starting from startDir and walking up.
+// Having more than one config format in the same directory is an error.
 func FindConfigFile(startDir string) (string, error) {
 	dir := startDir
 	for {
-		path := filepath.Join(dir, "mngproj.toml")
-		if _, err := os.Stat(path); err == nil {
+		path, err := ConfigFileIn(dir)
+		if err != nil {
+			return "", err
+		}
+		if path != "" {
 			return path, nil
 		}
 
 		parent := filepath.Dir(dir)
 		if parent == dir {
-			return "", fmt.Errorf("mngproj.toml not found")
+			return "", ErrConfigNotFound
 		}
 		dir = parent
 	}
 }
 
-// FindAllProjectConfigs recursively finds all directories containing mngproj.toml
+// ConfigFileIn returns the project config file in dir, or "" when there is none
+func ConfigFileIn(dir string) (string, error) {
+	return fileIn(dir, config.ConfigFileNames, "config")
+}
+
+// fileIn returns the single existing file among names in dir, or "" when there is none
+func fileIn(dir string, names []string, kind string) (string, error) {
+	var found []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("multiple %s files found in %s: %s", kind, dir, strings.Join(found, ", "))
+}
+
+// FindAllProjectConfigs recursively finds all directories containing a project config
 // starting from the given rootDir. It returns a slice of absolute paths to these directories.
 func FindAllProjectConfigs(rootDir string) ([]string, error) {
 	var projectRoots []string
@@ -37,10 +70,13 @@ func FindAllProjectConfigs(rootDir string) ([]string, error) {
 			return nil
 		}
 
-		// Check if it's a directory and contains mngproj.toml
+		// Check if it's a directory and contains a project config
 		if d.IsDir() {
-			configPath := filepath.Join(path, "mngproj.toml")
-			if _, err := os.Stat(configPath); err == nil {
+			configPath, err := ConfigFileIn(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			if configPath != "" {
 				projectRoots = append(projectRoots, path)
 				// Do NOT SkipDir, continue searching for other projects within this project's subtree
 			}
@@ -54,3 +90,84 @@ func FindAllProjectConfigs(rootDir string) ([]string, error) {
 
 	return projectRoots, nil
 }
+
+// discoverComponents appends the components contributed by [discovery] paths.
+// Each matching directory becomes a component named after the directory unless its
+// component fragment sets a name. Directories without a fragment are identified by the
+// presets' detection rules and skipped when nothing matches. Directories already used
+// by a declared component are skipped.
+func (m *Manager) discoverComponents() error {
+	cfg := m.ProjectConfig
+	claimed := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, c := range cfg.Components {
+		claimed[filepath.Clean(c.Path)] = true
+		names[c.Name] = true
+	}
+
+	var detector *Detector
+	for _, pattern := range cfg.Discovery.Paths {
+		matches, err := filepath.Glob(filepath.Join(m.ProjectDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return fmt.Errorf("invalid discovery pattern %q: %w", pattern, err)
+		}
+		for _, dir := range matches {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(m.ProjectDir, dir)
+			if err != nil || claimed[rel] {
+				continue
+			}
+			claimed[rel] = true
+
+			fragment, err := fileIn(dir, config.ComponentFileNames, "component")
+			if err != nil {
+				return err
+			}
+			if fragment == "" {
+				if detector == nil {
+					if detector, err = NewDetector(m.Presets(), cfg.Resolution.RolePriority, m.Warnings); err != nil {
+						return fmt.Errorf("failed to load detection rules: %w", err)
+					}
+				}
+				types, err := detector.Types(dir)
+				if err != nil {
+					return err
+				}
+				if len(types) == 0 {
+					continue
+				}
+				name := filepath.Base(dir)
+				if names[name] {
+					return fmt.Errorf("duplicate component name found: %q (detected in %s)", name, dir)
+				}
+				names[name] = true
+				// Saving dependencies creates the fragment
+				origin := filepath.Join(dir, config.ComponentFileNames[0])
+				cfg.Components = append(cfg.Components, config.ComponentConfig{Name: name, Types: types, Path: rel, Origin: origin})
+				continue
+			}
+			comp, err := config.LoadComponentFragment(fragment)
+			if err != nil {
+				return err
+			}
+			if comp.Name == "" {
+				comp.Name = filepath.Base(dir)
+			}
+			if comp.Path == "" {
+				comp.Path = rel
+			} else if !filepath.IsAbs(comp.Path) {
+				comp.Path = filepath.Join(rel, comp.Path)
+			}
+			comp.Origin = fragment
+
+			if names[comp.Name] {
+				return fmt.Errorf("duplicate component name found: %q (discovered in %s)", comp.Name, fragment)
+			}
+			names[comp.Name] = true
+			cfg.Components = append(cfg.Components, *comp)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"mngproj/pkg/config"
+	"mngproj/pkg/secrets"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// prepareEnv computes the variables added to the process environment when running
+// a script of comp. Later sources win:
+//  1. Project env_files, then component env_files (missing files are skipped)
+//  2. Preset and component env (templated and expanded, "secret:" references decrypted,
+//     command values run in the component directory)
+//  3. MNGPROJ_ROOT and MNGPROJ_COMPONENT_ROOT
+//  4. The script's own env, processed like the component env
+//  5. Manager.ExtraEnv (--env / --env-file)
+//
+// Entries with prepend, append or default build on the value from the earlier
+// sources, falling back to the process environment.
+//
+// Dotenv files come first so that values written in the config are never silently
+// replaced by a stray .env. Port variables (PORT_<NAME>, MNGPROJ_SVC_<COMP>_<NAME>_URL)
+// are set before everything else; the component env may refer to them as ${PORT_HTTP}.
+//
+// Values may refer to other components as ${components.NAME.env.VAR} or
+// {{ (component "NAME").Env.VAR }}; references are resolved recursively.
+func (m *Manager) prepareEnv(comp *ResolvedComponent, scriptEnv map[string]config.EnvValue) (map[string]string, error) {
+	return m.buildEnv(comp, scriptEnv, []string{comp.Name})
+}
+
+// buildEnv implements prepareEnv. stack lists the components whose env is being
+// computed, to detect reference cycles.
+func (m *Manager) buildEnv(comp *ResolvedComponent, scriptEnv map[string]config.EnvValue, stack []string) (map[string]string, error) {
+	b, err := m.newEnvBuilder(comp, stack)
+	if err != nil {
+		return nil, err
+	}
+	return b.scriptEnv(scriptEnv)
+}
+
+// envBuilder holds the part of a component's env shared by all of its scripts (sources
+// 1 to 3 of prepareEnv), so that a run computes it once: command values run, secrets
+// are decrypted and references are resolved a single time.
+type envBuilder struct {
+	m        *Manager
+	comp     *ResolvedComponent
+	stack    []string
+	portVars map[string]string
+	base     map[string]string
+}
+
+// newEnvBuilder computes the component part of the env of comp
+func (m *Manager) newEnvBuilder(comp *ResolvedComponent, stack []string) (*envBuilder, error) {
+	portVars, err := m.portEnv(comp.Name)
+	if err != nil {
+		return nil, err
+	}
+	b := &envBuilder{m: m, comp: comp, stack: stack, portVars: portVars, base: maps.Clone(portVars)}
+
+	// Dotenv values are taken literally, they are not expanded again
+	var envFiles []string
+	for _, f := range m.ProjectConfig.Project.EnvFiles {
+		envFiles = append(envFiles, absPath(m.ProjectDir, f))
+	}
+	envFiles = append(envFiles, comp.EnvFiles...)
+	for _, path := range envFiles {
+		values, err := config.LoadDotenv(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %w", comp.Name, err)
+		}
+		maps.Copy(b.base, values)
+	}
+
+	for k, v := range comp.Env {
+		value, err := b.resolveValue(b.base, k, v)
+		if err != nil {
+			return nil, err
+		}
+		b.base[k] = value
+	}
+	// Inject MNGPROJ_ROOT and MNGPROJ_COMPONENT_ROOT
+	b.base["MNGPROJ_ROOT"] = m.ProjectDir
+	b.base["MNGPROJ_COMPONENT_ROOT"] = comp.AbsPath
+	return b, nil
+}
+
+// scriptEnv returns the full env of a script with the given own env (sources 4 and 5)
+func (b *envBuilder) scriptEnv(scriptEnv map[string]config.EnvValue) (map[string]string, error) {
+	envMap := maps.Clone(b.base)
+	for k, v := range scriptEnv {
+		value, err := b.resolveValue(envMap, k, v)
+		if err != nil {
+			return nil, err
+		}
+		envMap[k] = value
+	}
+	maps.Copy(envMap, b.m.ExtraEnv)
+	return envMap, nil
+}
+
+// resolveValue computes the value of entry k; modifiers build on the value in envMap
+func (b *envBuilder) resolveValue(envMap map[string]string, k string, entry config.EnvValue) (string, error) {
+	var base string
+	var err error
+	switch {
+	case entry.Cmd != "":
+		if base, err = b.m.commandValue(b.comp, entry); err != nil {
+			return "", fmt.Errorf("env %s: %w", k, err)
+		}
+	case !entry.IsModifier():
+		if base, err = b.resolveString(k, entry.Value); err != nil {
+			return "", err
+		}
+	default:
+		// Modifiers build on the value set so far, or the process environment
+		value, ok := envMap[k]
+		if !ok {
+			value = os.Getenv(k)
+		}
+		base = value
+		if base == "" && entry.Default != "" {
+			if base, err = b.resolveString(k, entry.Default); err != nil {
+				return "", err
+			}
+		}
+	}
+	if entry.Prepend == "" && entry.Append == "" {
+		return base, nil
+	}
+	prepend, err := b.resolveString(k, entry.Prepend)
+	if err != nil {
+		return "", err
+	}
+	appended, err := b.resolveString(k, entry.Append)
+	if err != nil {
+		return "", err
+	}
+	return config.JoinPathList(prepend, base, appended), nil
+}
+
+// resolveString decrypts a secret reference, or renders and expands v
+func (b *envBuilder) resolveString(k, v string) (string, error) {
+	m, comp := b.m, b.comp
+	// Secret references are decrypted as-is, without templating or expansion
+	if name, ok := strings.CutPrefix(v, secrets.RefPrefix); ok {
+		value, err := m.secretValue(comp.Name, name)
+		if err != nil {
+			return "", fmt.Errorf("env %s: %w", k, err)
+		}
+		return value, nil
+	}
+	// Render preset params, e.g. {{.Params.port}}
+	if strings.Contains(v, "{{") {
+		lookup := func(name string) (*ComponentView, error) {
+			return m.componentView(name, b.stack)
+		}
+		rendered, err := renderTemplate(v, ScriptContext{Name: comp.Name, Params: comp.Params, lookup: lookup})
+		if err != nil {
+			return "", fmt.Errorf("env %s: %w", k, err)
+		}
+		v = rendered
+	}
+	// Expand values like $HOME, ${MNGPROJ_ROOT}, etc. The first failed component
+	// reference is kept in refErr.
+	var refErr error
+	expanded := os.Expand(v, func(key string) string {
+		switch key {
+		case "MNGPROJ_ROOT":
+			return m.ProjectDir
+		case "MNGPROJ_COMPONENT_ROOT", "COMPONENT_ROOT":
+			return comp.AbsPath
+		}
+		if value, ok := b.portVars[key]; ok {
+			return value
+		}
+		if ref, ok := strings.CutPrefix(key, "components."); ok {
+			value, err := m.componentVar(ref, b.stack)
+			if err != nil && refErr == nil {
+				refErr = err
+			}
+			return value
+		}
+		return os.Getenv(key)
+	})
+	if refErr != nil {
+		return "", fmt.Errorf("env %s: %w", k, refErr)
+	}
+	return expanded, nil
+}
+
+// absPath resolves p relative to base unless it is already absolute
+func absPath(base, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// defaultEnvCmdTimeout bounds env commands without a timeout
+const defaultEnvCmdTimeout = 10 * time.Second
+
+// commandValue runs the command of a computed env value in the component directory and
+// returns its trimmed output. With cache = "run" the output is reused for the rest of
+// this mngproj invocation.
+func (m *Manager) commandValue(comp *ResolvedComponent, entry config.EnvValue) (string, error) {
+	switch entry.Cache {
+	case config.EnvCacheNone, config.EnvCacheRun:
+	default:
+		return "", fmt.Errorf("invalid cache %q, expected %q or none", entry.Cache, config.EnvCacheRun)
+	}
+	timeout := defaultEnvCmdTimeout
+	if entry.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(entry.Timeout); err != nil {
+			return "", fmt.Errorf("invalid timeout %q: %w", entry.Timeout, err)
+		}
+	}
+
+	cacheKey := comp.AbsPath + "\x00" + entry.Cmd
+	if entry.Cache == config.EnvCacheRun {
+		m.envCacheMu.Lock()
+		defer m.envCacheMu.Unlock()
+		if value, ok := m.envCache[cacheKey]; ok {
+			return value, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shell, shellArgs := shellCommand("")
+	cmd := exec.CommandContext(ctx, shell, append(shellArgs, entry.Cmd)...)
+	cmd.Dir = comp.AbsPath
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command %q timed out after %s", entry.Cmd, timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %q failed: %w: %s", entry.Cmd, err, msg)
+		}
+		return "", fmt.Errorf("command %q failed: %w", entry.Cmd, err)
+	}
+	value := strings.TrimSpace(string(out))
+
+	if entry.Cache == config.EnvCacheRun {
+		if m.envCache == nil {
+			m.envCache = make(map[string]string)
+		}
+		m.envCache[cacheKey] = value
+	}
+	return value, nil
+}
@@ -1,70 +1,294 @@
 package manager
 
 import (
-	"bytes"
 	"fmt"
 	"io"
+	"maps"
+	"mngproj/pkg/config"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
 	"strings"
-	"text/template"
+	"sync"
+	"time"
 )
 
+// ScriptContext is the data available to script and env templates
 type ScriptContext struct {
-	Args []string
-	Env  map[string]string
+	Name   string
+	Args   []string
+	Env    map[string]string
+	Params map[string]any
+
+	lookup func(name string) (*ComponentView, error) // Backs the `component` template function
 }
 
-// ExecuteScript runs the script and waits for it to finish
+// ExecuteScript runs the script and waits for it to finish. Its pre<script> hooks run
+// first and its post<script> hooks after it; the first failure stops the sequence.
+// A script with a timeout is killed when a step runs too long, and failed runs are
+// retried up to its retries. Steps may reference other scripts as @script or @comp:script.
 func (m *Manager) ExecuteScript(componentName, scriptName string, args []string, stdout, stderr io.Writer) error {
-	cmd, err := m.ExecuteScriptAsync(componentName, scriptName, args, stdout, stderr)
+	return m.execute(newInvocation(), componentName, scriptName, args, stdout, stderr)
+}
+
+// ExecuteScriptAsync prepares and starts the script, returning the running process.
+// The caller is responsible for waiting on it. Pre hooks and all but the last step
+// run to completion first; post hooks and retries only apply to ExecuteScript.
+// The script's timeout is enforced by killing its process group until Wait returns.
+func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []string, stdout, stderr io.Writer) (*ScriptProcess, error) {
+	return m.executeAsync(newInvocation(), componentName, scriptName, args, stdout, stderr)
+}
+
+func (m *Manager) execute(inv *invocation, componentName, scriptName string, args []string, stdout, stderr io.Writer) error {
+	leave, err := inv.enter(componentName, scriptName)
 	if err != nil {
 		return err
 	}
-	return cmd.Wait()
+	defer leave()
+
+	comp, script, err := m.lookupScript(componentName, scriptName)
+	if err != nil {
+		return err
+	}
+	envs, env, err := m.runEnv(comp, script)
+	if err != nil {
+		return err
+	}
+	if err := m.runHooks(inv, comp, envs, "pre"+scriptName, stdout, stderr); err != nil {
+		return err
+	}
+	if err := m.runScript(inv, comp, scriptName, script, env, args, stdout, stderr); err != nil {
+		return err
+	}
+	return m.runHooks(inv, comp, envs, "post"+scriptName, stdout, stderr)
 }
 
-// ExecuteScriptAsync prepares and starts the script, returning the *exec.Cmd object
-// The caller is responsible for waiting on the command.
-func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []string, stdout, stderr io.Writer) (*exec.Cmd, error) {
-	comp, err := m.ResolveComponent(componentName)
+func (m *Manager) executeAsync(inv *invocation, componentName, scriptName string, args []string, stdout, stderr io.Writer) (*ScriptProcess, error) {
+	leave, err := inv.enter(componentName, scriptName)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	comp, script, err := m.lookupScript(componentName, scriptName)
+	if err != nil {
+		return nil, err
+	}
+	envs, env, err := m.runEnv(comp, script)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.runHooks(inv, comp, envs, "pre"+scriptName, stdout, stderr); err != nil {
+		return nil, err
+	}
+	commands := script.Commands()
+	last := len(commands) - 1
+	if err := m.runSteps(inv, comp, scriptName, script, env, last, args, stdout, stderr); err != nil {
+		return nil, err
+	}
+
+	// A trailing reference starts the referenced script instead
+	if ref, ok := parseRef(comp, commands[last]); ok {
+		return m.executeAsync(inv, ref.component, ref.script, append(ref.args, args...), stdout, stderr)
+	}
+	cmd, err := m.startCommand(comp, scriptName, script, env, last, args, stdout, stderr)
 	if err != nil {
 		return nil, err
 	}
+	timeout, _ := time.ParseDuration(script.Timeout)
+	return &ScriptProcess{Cmd: cmd, deadline: newDeadline(cmd, timeout)}, nil
+}
 
-	cmdStr, ok := comp.Scripts[scriptName]
+// runEnv computes the env of comp once for a run and returns the env of script on
+// top of it, after checking the component's required env against it
+func (m *Manager) runEnv(comp *ResolvedComponent, script config.Script) (*envBuilder, map[string]string, error) {
+	envs, err := m.newEnvBuilder(comp, []string{comp.Name})
+	if err != nil {
+		return nil, nil, err
+	}
+	env, err := envs.scriptEnv(script.Env)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkScriptEnv(comp, env); err != nil {
+		return nil, nil, err
+	}
+	return envs, env, nil
+}
+
+// runHooks runs the hooks registered under name, each with its own env on top of the run's
+func (m *Manager) runHooks(inv *invocation, comp *ResolvedComponent, envs *envBuilder, name string, stdout, stderr io.Writer) error {
+	for _, hook := range comp.Hooks[name] {
+		env, err := envs.scriptEnv(hook.Env)
+		if err != nil {
+			return err
+		}
+		if err := m.runScript(inv, comp, name, hook, env, nil, stdout, stderr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupScript resolves the component and validates the requested script
+func (m *Manager) lookupScript(componentName, scriptName string) (*ResolvedComponent, config.Script, error) {
+	if m.isProjectScope(componentName) {
+		comp, err := m.ProjectScripts()
+		if err != nil {
+			return nil, config.Script{}, err
+		}
+		script, ok := comp.Scripts[scriptName]
+		if !ok {
+			return nil, script, fmt.Errorf("project script %q not defined", scriptName)
+		}
+		return comp, script, validateScript(scriptName, script)
+	}
+	comp, err := m.ResolveComponent(componentName)
+	if err != nil {
+		return nil, config.Script{}, err
+	}
+
+	script, ok := comp.Scripts[scriptName]
 	if !ok {
-		return nil, fmt.Errorf("script %q not defined for component %q", scriptName, componentName)
+		return nil, script, fmt.Errorf("script %q not defined for component %q", scriptName, componentName)
 	}
+	return comp, script, validateScript(scriptName, script)
+}
+
+// validateScript rejects scripts that cannot run as declared
+func validateScript(scriptName string, script config.Script) error {
+	if script.Cmd != "" && len(script.Steps) > 0 {
+		return fmt.Errorf("script %q sets both cmd and steps", scriptName)
+	}
+	if script.Timeout != "" {
+		if _, err := time.ParseDuration(script.Timeout); err != nil {
+			return fmt.Errorf("script %q: invalid timeout %q: %w", scriptName, script.Timeout, err)
+		}
+	}
+	return nil
+}
+
+// runScript runs all steps of script, retrying the whole sequence on failure
+func (m *Manager) runScript(inv *invocation, comp *ResolvedComponent, scriptName string, script config.Script, env map[string]string, args []string, stdout, stderr io.Writer) error {
+	for attempt := 0; ; attempt++ {
+		err := m.runSteps(inv, comp, scriptName, script, env, len(script.Commands()), args, stdout, stderr)
+		if err == nil || attempt >= script.Retries {
+			if err != nil && script.Retries > 0 {
+				return fmt.Errorf("script %q failed after %d attempts: %w", scriptName, attempt+1, err)
+			}
+			return err
+		}
+		errW := stderr
+		if errW == nil {
+			errW = os.Stderr
+		}
+		fmt.Fprintf(errW, "[%s] %s failed (%v), retrying (%d/%d)\n", comp.Name, scriptName, err, attempt+1, script.Retries)
+	}
+}
+
+// runSteps runs the first n steps of script with env in order, stopping at the first
+// failure. Reference steps run the referenced script with its hooks.
+func (m *Manager) runSteps(inv *invocation, comp *ResolvedComponent, scriptName string, script config.Script, env map[string]string, n int, args []string, stdout, stderr io.Writer) error {
+	timeout, _ := time.ParseDuration(script.Timeout)
+	commands := script.Commands()
+	steps := len(commands)
+	for i := 0; i < n; i++ {
+		var err error
+		if ref, ok := parseRef(comp, commands[i]); ok {
+			refArgs := ref.args
+			if i == steps-1 {
+				refArgs = append(refArgs, args...)
+			}
+			err = m.runRef(inv, ref, refArgs, stdout, stderr)
+		} else {
+			var cmd *exec.Cmd
+			if cmd, err = m.startCommand(comp, scriptName, script, env, i, args, stdout, stderr); err != nil {
+				return err
+			}
+			err = waitTimeout(cmd, timeout)
+		}
+		if err != nil {
+			if steps > 1 {
+				return fmt.Errorf("script %q step %d/%d: %w", scriptName, i+1, steps, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// waitTimeout waits for cmd, killing it once timeout has elapsed (zero means no limit)
+func waitTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	return (&ScriptProcess{Cmd: cmd, deadline: newDeadline(cmd, timeout)}).Wait()
+}
+
+// ScriptProcess is a script started by ExecuteScriptAsync
+type ScriptProcess struct {
+	*exec.Cmd
+	deadline *deadline // nil without a timeout
+}
+
+// Wait waits for the script to exit. A script killed for running past its timeout
+// returns a timeout error.
+func (p *ScriptProcess) Wait() error {
+	err := p.Cmd.Wait()
+	if p.deadline != nil && p.deadline.stop() {
+		return fmt.Errorf("timed out after %s", p.deadline.timeout)
+	}
+	return err
+}
+
+// deadline kills the process group of a started command once its timeout elapses,
+// unless the command has been waited for by then
+type deadline struct {
+	timeout  time.Duration
+	timer    *time.Timer
+	mu       sync.Mutex
+	exited   bool
+	timedOut bool
+}
+
+// newDeadline starts the timer of cmd; it returns nil when timeout is not positive
+func newDeadline(cmd *exec.Cmd, timeout time.Duration) *deadline {
+	if timeout <= 0 {
+		return nil
+	}
+	d := &deadline{timeout: timeout}
+	d.timer = time.AfterFunc(timeout, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// Once waited for, the PID may belong to another process
+		if !d.exited {
+			d.timedOut = true
+			killProcessGroup(cmd)
+		}
+	})
+	return d
+}
+
+// stop disarms the timer after the command was waited for and reports whether it had fired
+func (d *deadline) stop() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.exited = true
+	d.timer.Stop()
+	return d.timedOut
+}
+
+// startCommand starts one step of a script with the variables of envMap added to the
+// process environment. Arguments are available to every step's template and appended
+// to the last step when it does not reference .Args.
+func (m *Manager) startCommand(comp *ResolvedComponent, scriptName string, script config.Script, envMap map[string]string, step int, args []string, stdout, stderr io.Writer) (*exec.Cmd, error) {
+	commands := script.Commands()
+	cmdStr := commands[step]
 
-	// Prepare environment
-	envMap := make(map[string]string)
 	env := os.Environ()
-	
-	// Mapper for variable expansion
-	expandMapper := func(key string) string {
-		switch key {
-		case "MNGPROJ_ROOT":
-			return m.ProjectDir
-		case "MNGPROJ_COMPONENT_ROOT", "COMPONENT_ROOT":
-			return comp.AbsPath
-		}
-		return os.Getenv(key)
-	}
-
-	for k, v := range comp.Env {
-		// Expand values like $HOME, ${MNGPROJ_ROOT}, etc.
-		expandedV := os.Expand(v, expandMapper)
-		env = append(env, fmt.Sprintf("%s=%s", k, expandedV))
-		envMap[k] = expandedV
-	}
-	// Inject MNGPROJ_ROOT and MNGPROJ_COMPONENT_ROOT
-	env = append(env, fmt.Sprintf("MNGPROJ_ROOT=%s", m.ProjectDir))
-	envMap["MNGPROJ_ROOT"] = m.ProjectDir
-	env = append(env, fmt.Sprintf("MNGPROJ_COMPONENT_ROOT=%s", comp.AbsPath))
-	envMap["MNGPROJ_COMPONENT_ROOT"] = comp.AbsPath
+	for _, k := range slices.Sorted(maps.Keys(envMap)) {
+		env = append(env, fmt.Sprintf("%s=%s", k, envMap[k]))
+	}
 
 	// Handle "file:" prefix
 	if strings.HasPrefix(cmdStr, "file:") {
@@ -72,7 +296,7 @@ func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []st
 		if !filepath.IsAbs(scriptPath) {
 			scriptPath = filepath.Join(m.ProjectDir, scriptPath)
 		}
-		
+
 		content, err := os.ReadFile(scriptPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read script file %s: %w", scriptPath, err)
@@ -81,33 +305,30 @@ func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []st
 	}
 
 	// Process Command String
-	var fullCmd string
-	
+	fullCmd := cmdStr
+
 	// Check if template syntax is used
 	if strings.Contains(cmdStr, "{{") {
-		tmpl, err := template.New("script").Parse(cmdStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse script template: %w", err)
-		}
-		
 		ctx := ScriptContext{
-			Args: args,
-			Env:  envMap,
-		}
-		
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, ctx); err != nil {
-			return nil, fmt.Errorf("failed to execute script template: %w", err)
+			Name:   comp.Name,
+			Args:   args,
+			Env:    envMap,
+			Params: comp.Params,
+			lookup: func(name string) (*ComponentView, error) {
+				return m.componentView(name, []string{comp.Name})
+			},
 		}
-		fullCmd = buf.String()
-	} else {
-		// Legacy/Simple behavior: append args
-		fullCmd = cmdStr
-		if len(args) > 0 {
-			fullCmd += " " + strings.Join(args, " ")
+		var err error
+		if fullCmd, err = renderTemplate(cmdStr, ctx); err != nil {
+			return nil, fmt.Errorf("script %q: %w", scriptName, err)
 		}
 	}
 
+	// Scripts that do not place .Args themselves get the arguments appended
+	if step == len(commands)-1 && !strings.Contains(cmdStr, ".Args") && len(args) > 0 {
+		fullCmd += " " + strings.Join(args, " ")
+	}
+
 	// Determine outputs
 	outW := stdout
 	if outW == nil {
@@ -119,21 +340,24 @@ func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []st
 	}
 
 	// Log execution
-	if stdout == nil { 
-		fmt.Printf("[%s] Executing: %s\n", componentName, fullCmd)
-	}
-
-	var shell, flag string
-	if runtime.GOOS == "windows" {
-		shell = "powershell"
-		flag = "-Command"
-	} else {
-		shell = "sh"
-		flag = "-c"
+	if stdout == nil {
+		fmt.Printf("[%s] Executing: %s\n", comp.Name, m.Redactor().Redact(fullCmd))
 	}
 
-	cmd := exec.Command(shell, flag, fullCmd)
+	shell, shellArgs := shellCommand(script.Shell)
+	cmd := exec.Command(shell, append(shellArgs, fullCmd)...)
 	cmd.Dir = comp.AbsPath
+	if script.Cwd != "" {
+		cmd.Dir = absPath(comp.AbsPath, script.Cwd)
+	}
+	if script.Timeout != "" {
+		// Timed scripts run in their own process group so that a timeout also kills the
+		// commands the shell started. Other scripts stay in the foreground group, where
+		// they can read from the terminal.
+		setProcessGroup(cmd)
+		// Children of a killed shell may keep the output pipes open; don't wait for them
+		cmd.WaitDelay = time.Second
+	}
 	cmd.Env = env
 	cmd.Stdout = outW
 	cmd.Stderr = errW
@@ -142,6 +366,49 @@ func (m *Manager) ExecuteScriptAsync(componentName, scriptName string, args []st
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
-	
+
 	return cmd, nil
 }
+
+// shellCommand returns the program and leading arguments used to run a command string
+func shellCommand(shell string) (string, []string) {
+	switch {
+	case shell == "" && runtime.GOOS == "windows":
+		return "powershell", []string{"-Command"}
+	case shell == "":
+		return "sh", []string{"-c"}
+	}
+	switch strings.TrimSuffix(filepath.Base(shell), ".exe") {
+	case "powershell", "pwsh":
+		return shell, []string{"-Command"}
+	case "cmd":
+		return shell, []string{"/C"}
+	}
+	return shell, []string{"-c"}
+}
+
+// isHookName reports whether name looks like a pre/post hook, e.g. "prebuild"
+func isHookName(name string) bool {
+	return hookTarget(name) != ""
+}
+
+// hookTarget returns the script a hook name refers to, or "" for other names
+func hookTarget(name string) string {
+	for _, prefix := range []string{"pre", "post"} {
+		if target, ok := strings.CutPrefix(name, prefix); ok {
+			return target
+		}
+	}
+	return ""
+}
+
+// appendScript appends script unless an identical definition is already present,
+// as happens when presets share a parent
+func appendScript(list []config.Script, script config.Script) []config.Script {
+	for _, s := range list {
+		if reflect.DeepEqual(s, script) {
+			return list
+		}
+	}
+	return append(list, script)
+}
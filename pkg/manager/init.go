@@ -4,35 +4,67 @@ import (
 	"fmt"
 	"mngproj/pkg/config"
 	"os"
+	"strings"
 )
 
+// InitializeProject creates mngproj.toml and .gitignore in the current directory.
+// When targetType is empty the component types are detected from the files on disk,
+// falling back to a single component of the user's init_type (go by default).
 func InitializeProject(targetType string) error {
-	fmt.Printf("Initializing new mngproj.toml (type: %s)...\n", targetType)
-	content := fmt.Sprintf(`[project]
-name = "new-project"
-description = "Created by mngproj init"
+	cwd, _ := os.Getwd()
+	layers := PresetSearchPath(cwd)
 
-[[components]]
-name = "app"
-type = "%s"
-path = "."
-`, targetType)
+	var comps []config.ComponentConfig
+	if targetType == "" {
+		detector, err := NewDetector(config.NewPresetRegistry(layers), nil, os.Stderr)
+		if err != nil {
+			return fmt.Errorf("failed to load detection rules: %w", err)
+		}
+		if comps, err = detector.Scan(cwd); err != nil {
+			return err
+		}
+		if len(comps) == 0 {
+			targetType = "go"
+			if global, err := config.LoadGlobalConfig(); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			} else if global.InitType != "" {
+				targetType = global.InitType
+			}
+			fmt.Printf("No component types detected, defaulting to %s\n", targetType)
+		} else {
+			fmt.Println("Initializing new mngproj.toml (detected components)...")
+			for _, c := range comps {
+				fmt.Printf("  %s (%s): %s\n", c.Name, c.Path, strings.Join(c.Types, ", "))
+			}
+		}
+	}
+	if targetType != "" {
+		fmt.Printf("Initializing new mngproj.toml (type: %s)...\n", targetType)
+		comps = []config.ComponentConfig{{Name: "app", Types: []string{targetType}, Path: "."}}
+	}
 
-	if err := os.WriteFile("mngproj.toml", []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to create mngproj.toml: %w", err)
+	if err := writeNewConfig("mngproj.toml", "new-project", comps); err != nil {
+		return err
 	}
 	fmt.Println("Created mngproj.toml")
 
 	// Generate .gitignore
-	presetsDir := DeterminePresetsDir()
-	preset, err := config.LoadPreset(presetsDir, targetType)
-	gitignoreContent := "# mngproj generated\n.libs/\n"
-	if err == nil {
-		for _, pattern := range preset.Gitignore {
-			gitignoreContent += pattern + "\n"
+	gitignoreContent := "# mngproj generated\n.libs/\n.mngproj.key\nmngproj.local.*\n"
+	seen := make(map[string]bool)
+	for _, c := range comps {
+		for _, typeName := range declaredTypes(&c) {
+			preset, err := config.LoadPresetFromLayers(layers, typeName)
+			if err != nil {
+				fmt.Printf("Warning: failed to load '%s' preset for gitignore: %v\n", typeName, err)
+				continue
+			}
+			for _, pattern := range preset.Metadata.Gitignore {
+				if !seen[pattern] {
+					seen[pattern] = true
+					gitignoreContent += pattern + "\n"
+				}
+			}
 		}
-	} else {
-		fmt.Printf("Warning: failed to load '%s' preset for gitignore: %v (dir: %s)\n", targetType, err, presetsDir)
 	}
 	if err := os.WriteFile(".gitignore", []byte(gitignoreContent), 0644); err != nil {
 		fmt.Printf("Warning: failed to create .gitignore: %v\n", err)
@@ -41,3 +73,29 @@ path = "."
 	}
 	return nil
 }
+
+// writeNewConfig writes a minimal mngproj.toml declaring comps
+func writeNewConfig(path, projectName string, comps []config.ComponentConfig) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, `[project]
+name = %q
+description = "Created by mngproj init"
+schema = %d
+`, projectName, config.CurrentSchema)
+	for _, c := range comps {
+		fmt.Fprintf(&b, "\n[[components]]\nname = %q\n", c.Name)
+		// The legacy single `type` is never written; new configs use the current schema
+		types := declaredTypes(&c)
+		quoted := make([]string, len(types))
+		for i, t := range types {
+			quoted[i] = fmt.Sprintf("%q", t)
+		}
+		fmt.Fprintf(&b, "types = [%s]\n", strings.Join(quoted, ", "))
+		fmt.Fprintf(&b, "path = %q\n", c.Path)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"fmt"
+	"mngproj/pkg/config"
+)
+
+// mergeLocalConfig merges mngproj.local.* from the config directory over the project
+// config. The merged values only live in memory; saving goes through saveLocalDependencies
+// or re-reads the shared file.
+func (m *Manager) mergeLocalConfig() error {
+	path, err := fileIn(m.configDir(), config.LocalConfigFileNames, "local config")
+	if err != nil || path == "" {
+		return err
+	}
+	local, err := config.LoadLocalConfig(path)
+	if err != nil {
+		return err
+	}
+	config.MergeLocalConfig(m.ProjectConfig, local)
+	m.LocalPath = path
+	return nil
+}
+
+// saveLocalDependencies saves comp's dependencies to the local override when that file
+// owns them: it declares the component's dependencies or is the only file declaring
+// the component. It reports whether it saved.
+func (m *Manager) saveLocalDependencies(comp *config.ComponentConfig) (bool, error) {
+	if m.LocalPath == "" {
+		return false, nil
+	}
+	local, err := config.LoadLocalConfig(m.LocalPath)
+	if err != nil {
+		return false, err
+	}
+	owned := false
+	for _, c := range local.Components {
+		if c.Name == comp.Name && (c.Dependencies != nil || !m.declaredInSharedConfig(comp)) {
+			owned = true
+		}
+	}
+	if !owned {
+		return false, nil
+	}
+	if err := config.SetLocalDependencies(m.LocalPath, comp.Name, comp.Dependencies); err != nil {
+		return false, fmt.Errorf("failed to save local config: %w", err)
+	}
+	return true, nil
+}
+
+// declaredInSharedConfig reports whether comp is defined by a committed file: a component
+// fragment or the project config
+func (m *Manager) declaredInSharedConfig(comp *config.ComponentConfig) bool {
+	if comp.Origin != "" {
+		return true
+	}
+	cfg, err := config.LoadProjectConfig(m.ConfigPath)
+	if err != nil {
+		// Without a readable shared config, the local file is the only definition
+		return false
+	}
+	for _, c := range cfg.Components {
+		if c.Name == comp.Name {
+			return true
+		}
+	}
+	return false
+}
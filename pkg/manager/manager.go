@@ -1,20 +1,62 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"mngproj/pkg/config"
+	"mngproj/pkg/secrets"
+	"mngproj/pkg/utils"
+	"mngproj/presets"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 )
 
 type Manager struct {
 	ProjectConfig *config.ProjectConfig
 	ProjectDir    string
-	PresetsDir    string
+	ConfigPath    string               // Path of the loaded project config (mngproj.toml, .yaml, .yml or .json)
+	LocalPath     string               // Path of the merged per-developer override (mngproj.local.toml), if any
+	PresetsDir    string               // Optional explicit presets directory, searched before all other layers
+	ExtraEnv      map[string]string    // Overrides from --env / --env-file, applied after everything else
+	Profile       string               // Active profile for `when` conditions, from --profile or MNGPROJ_PROFILE
+	Global        *config.GlobalConfig // Personal defaults from the user config
+	Warnings      io.Writer            // Receives warnings while loading, e.g. about an outdated schema; nil discards them
+
+	allComponents []config.ComponentConfig // Components before `when` filtering
+
+	presetsOnce sync.Once
+	presets     *config.PresetRegistry
+
+	envCacheMu sync.Mutex
+	envCache   map[string]string // Outputs of env commands with cache = "run"
+
+	portsMu sync.Mutex
+	ports   map[string]map[string]int // Assigned ports by component and port name
+
+	secretsMu   sync.Mutex
+	secretStore *secrets.Store
+	redactor    utils.Redactor
+}
+
+// Options tune how NewWithOptions loads a project
+type Options struct {
+	Warnings     io.Writer // Receives warnings while loading; nil discards them
+	IgnoreSchema bool      // Skip the outdated-schema warning, for commands that migrate the config
 }
 
+// New loads the project containing startDir, printing warnings to stderr
 func New(startDir string) (*Manager, error) {
+	return NewWithOptions(startDir, Options{Warnings: os.Stderr})
+}
+
+// NewWithOptions loads the project containing startDir. Warnings are written once
+// here, so that re-reading the config later in the command stays silent.
+func NewWithOptions(startDir string, opts Options) (*Manager, error) {
 	configPath, err := FindConfigFile(startDir)
 	if err != nil {
 		return nil, err
@@ -24,6 +66,10 @@ func New(startDir string) (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	global, err := config.LoadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	configDir := filepath.Dir(configPath)
 	projectDir := configDir
@@ -36,825 +82,489 @@ func New(startDir string) (*Manager, error) {
 		}
 	}
 
-	return &Manager{
+	m := &Manager{
 		ProjectConfig: cfg,
 		ProjectDir:    projectDir,
-		PresetsDir:    DeterminePresetsDir(),
-	}, nil
+		ConfigPath:    configPath,
+		Profile:       os.Getenv(ProfileEnvVar),
+		Global:        global,
+		Warnings:      opts.Warnings,
+	}
+	if schema := config.SchemaOf(cfg); schema < config.CurrentSchema && !opts.IgnoreSchema {
+		m.warnf("%s uses config schema %d (current: %d); run `mngproj migrate` to update it", configPath, schema, config.CurrentSchema)
+	}
+	if err := m.discoverComponents(); err != nil {
+		return nil, err
+	}
+	if err := m.mergeLocalConfig(); err != nil {
+		return nil, err
+	}
+	if err := m.checkReservedNames(); err != nil {
+		return nil, err
+	}
+	if err := m.filterComponents(); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func DeterminePresetsDir() string {
-	// Priority:
-	// 1. MNGPROJ_PRESETS_DIR env var
-	// 2. $HOME/.config/mngproj/presets
-	// 3. ./presets (relative to executable - for dev)
-	
-	if env := os.Getenv("MNGPROJ_PRESETS_DIR"); env != "" {
-		return env
-	}
-
-	home, err := os.UserHomeDir()
-	if err == nil {
-		path := filepath.Join(home, ".config", "mngproj", "presets")
-		if _, err := os.Stat(path); err == nil {
-			return path
-		}
+// warnf writes a warning line to m.Warnings, if set
+func (m *Manager) warnf(format string, args ...any) {
+	if m.Warnings != nil {
+		fmt.Fprintf(m.Warnings, "Warning: "+format+"\n", args...)
 	}
-	
-	// Fallback to local presets (e.g. for development)
-	return "presets"
 }
 
-// ResolvedComponent represents a fully merged configuration for a component
-
-type ResolvedComponent struct {
+// configDir returns the directory holding mngproj.toml
+func (m *Manager) configDir() string {
+	if m.ConfigPath != "" {
+		return filepath.Dir(m.ConfigPath)
+	}
+	return m.ProjectDir
+}
 
-	Name         string
+// PresetLayers returns the preset search path of this project, highest priority first
+func (m *Manager) PresetLayers() []config.PresetLayer {
+	var layers []config.PresetLayer
+	if len(m.ProjectConfig.Presets) > 0 {
+		layers = append(layers, config.InlinePresetLayer(filepath.Base(m.ConfigPath), m.ProjectConfig.Presets))
+	}
+	if m.PresetsDir != "" {
+		layers = append(layers, config.DirLayer(m.PresetsDir))
+	}
+	return append(layers, PresetSearchPath(m.configDir())...)
+}
 
-	Type         string
+// Presets returns the preset registry of this project. The search path is indexed
+// on first use and parsed presets are cached for the lifetime of the Manager.
+func (m *Manager) Presets() *config.PresetRegistry {
+	m.presetsOnce.Do(func() {
+		m.presets = config.NewPresetRegistry(m.PresetLayers())
+	})
+	return m.presets
+}
 
-	AbsPath      string
+// PresetSearchPath returns the layered preset search path. Higher layers win:
+// 1. presets/ next to mngproj.toml (skipped when configDir is empty)
+// 2. $XDG_CONFIG_HOME/mngproj/presets (usually ~/.config/mngproj/presets)
+// 3. preset_dirs from the user config (~/.config/mngproj/config.toml)
+// 4. Directories listed in MNGPROJ_PRESETS_DIR (path list)
+// 5. Presets embedded in the binary
+func PresetSearchPath(configDir string) []config.PresetLayer {
+	var layers []config.PresetLayer
+	if configDir != "" {
+		layers = append(layers, config.DirLayer(filepath.Join(configDir, "presets")))
+	}
 
-	ManifestFile string
+	if userDir, err := os.UserConfigDir(); err == nil {
+		layers = append(layers, config.DirLayer(filepath.Join(userDir, "mngproj", "presets")))
+	}
 
-	Env          map[string]string
+	// An unreadable user config is reported when the manager loads it
+	if global, err := config.LoadGlobalConfig(); err == nil {
+		for _, dir := range global.PresetDirs {
+			layers = append(layers, config.DirLayer(dir))
+		}
+	}
 
-	Scripts      map[string]string
+	for _, dir := range filepath.SplitList(os.Getenv("MNGPROJ_PRESETS_DIR")) {
+		if dir != "" {
+			layers = append(layers, config.DirLayer(dir))
+		}
+	}
 
+	return append(layers, config.PresetLayer{Name: "embedded", FS: presets.FS})
 }
 
-
+// ResolvedComponent represents a fully merged configuration for a component
+type ResolvedComponent struct {
+	Name         string
+	Type         string
+	Types        []string // Effective preset types, including auto-required ones
+	AbsPath      string
+	ManifestFile string
+	Env          map[string]config.EnvValue
+	Scripts      map[string]config.Script
+	Params       map[string]any       // Preset parameters after component overrides
+	EnvFiles     []string             // Absolute paths of the component's dotenv files
+	RequiredEnv  []config.RequiredEnv // Preset requirements followed by the component's own
+
+	// ScriptSources maps each script to where it was defined: a preset name or "component"
+	ScriptSources map[string]string
+	// Hooks holds every definition of a pre<script>/post<script> hook, in preset order.
+	// Hooks from all presets run; a component-level hook replaces them.
+	Hooks map[string][]config.Script
+}
 
 // Default Role Priority Scores
-
 var defaultRolePriority = map[string]int{
-
 	"framework":       30,
-
 	"tool":            20,
-
 	"package_manager": 10,
-
 	"language":        0,
-
 }
 
+// rolePriorities collects the role scores used for comp: roles declared by its presets,
+// then the project's and the component's role_priority. Later entries win.
+func (m *Manager) rolePriorities(comp *config.ComponentConfig, typeNames []string, presets map[string]*config.PresetConfig) map[string]int {
+	priorities := make(map[string]int)
+	for _, tName := range typeNames {
+		maps.Copy(priorities, presets[tName].Metadata.Roles)
+	}
+	maps.Copy(priorities, m.ProjectConfig.Resolution.RolePriority)
+	if comp.Resolution != nil {
+		maps.Copy(priorities, comp.Resolution.RolePriority)
+	}
+	return priorities
+}
 
-
-func (m *Manager) getRoleScore(role string) int {
-
+// roleScore returns the priority of role, preferring the given overrides over the defaults
+func roleScore(overrides map[string]int, role string) int {
 	// 1. Check User Override
-
-	if m.ProjectConfig.Resolution.RolePriority != nil {
-
-		if score, ok := m.ProjectConfig.Resolution.RolePriority[role]; ok {
-
-			return score
-
-		}
-
+	if score, ok := overrides[role]; ok {
+		return score
 	}
-
 	// 2. Check Default
-
 	if score, ok := defaultRolePriority[role]; ok {
-
 		return score
-
 	}
-
 	return 0 // Default for unknown roles
-
 }
 
-
-
 func (m *Manager) ResolveComponent(name string) (*ResolvedComponent, error) {
-
 	var compConfig *config.ComponentConfig
-
 	for i := range m.ProjectConfig.Components {
-
 		if m.ProjectConfig.Components[i].Name == name {
-
 			compConfig = &m.ProjectConfig.Components[i]
-
 			break
-
 		}
-
 	}
-
 	if compConfig == nil {
-
 		return nil, fmt.Errorf("component %q not found", name)
-
 	}
 
-
-
-	// Normalize types
-
-	typeNames := compConfig.Types
-
-	if len(typeNames) == 0 && compConfig.Type != "" {
-
-		typeNames = []string{compConfig.Type}
-
+	// Normalize types, pulling in required presets and rejecting conflicts
+	typeNames, presets, err := m.expandTypes(compConfig)
+	if err != nil {
+		return nil, err
 	}
 
-
-
 	resolved := &ResolvedComponent{
-
 		Name:    compConfig.Name,
-
 		Type:    "",
-
+		Types:   typeNames,
 		AbsPath: filepath.Join(m.ProjectDir, compConfig.Path),
+		Env:     make(map[string]config.EnvValue),
+		Scripts: make(map[string]config.Script),
+		Params:  make(map[string]any),
 
-		Env:     make(map[string]string),
-
-		Scripts: make(map[string]string),
-
+		ScriptSources: make(map[string]string),
+		Hooks:         make(map[string][]config.Script),
 	}
-
-	if len(typeNames) > 0 {
-
-		resolved.Type = typeNames[0]
-
+	if declared := declaredTypes(compConfig); len(declared) > 0 {
+		resolved.Type = declared[0]
 	}
 
-
-
 	// map[scriptName]score
-
 	scriptScores := make(map[string]int)
-
 	maxManifestScore := -1
-
-
+	priorities := m.rolePriorities(compConfig, typeNames, presets)
+	// map[presetName]scripts, after the preset's overrides
+	scriptsByPreset := make(map[string]map[string]config.Script)
 
 	// 1. Apply Presets with Role-based Priority
-
 	for _, tName := range typeNames {
+		preset := presets[tName]
+		currentScore := roleScore(priorities, preset.Metadata.Role)
 
-		preset, err := config.LoadPreset(m.PresetsDir, tName)
-
+		// Scripts and env entries whose `when` condition fails are dropped
+		presetScripts, presetEnv, err := m.activeConfig(preset.Scripts, preset.Env, preset.Overrides, compConfig, typeNames)
 		if err != nil {
-
-			return nil, fmt.Errorf("failed to load preset %q: %w", tName, err)
-
+			return nil, fmt.Errorf("preset %q: %w", tName, err)
 		}
 
-
-
-		currentScore := m.getRoleScore(preset.Metadata.Role)
-
-
-
 		// Resolve ManifestFile
-
 		if preset.Metadata.ManifestFile != "" {
-
 			if currentScore > maxManifestScore {
-
 				resolved.ManifestFile = preset.Metadata.ManifestFile
-
 				maxManifestScore = currentScore
-
 			}
-
 		}
 
-
-
-		// Merge Env (Accumulate/Overwrite logic - last wins in types list)
-
-		for k, v := range preset.Env {
-
-			resolved.Env[k] = v
-
+		// Merge Params (last wins in types list)
+		for k, v := range preset.Params {
+			resolved.Params[k] = v
 		}
 
-
+		// Merge Env (last wins in types list; prepend/append/default build on earlier presets)
+		config.MergeEnv(resolved.Env, presetEnv)
+		resolved.RequiredEnv = config.MergeRequiredEnv(resolved.RequiredEnv, preset.RequiredEnv...)
 
 		// Merge Scripts (Priority based)
-
-		for script, cmd := range preset.Scripts {
+		scriptsByPreset[tName] = presetScripts
+		for script, cmd := range presetScripts {
+			// Hooks accumulate instead of overriding each other
+			if isHookName(script) {
+				resolved.Hooks[script] = appendScript(resolved.Hooks[script], cmd)
+			}
 
 			existingScore, exists := scriptScores[script]
 
-			
-
 			// Update if:
-
 			// 1. Script doesn't exist yet
-
 			// 2. Current preset has higher priority score
-
 			// 3. Scores are equal (Last Wins - user order preference)
-
 			if !exists || currentScore >= existingScore {
-
 				resolved.Scripts[script] = cmd
-
+				resolved.ScriptSources[script] = tName
 				scriptScores[script] = currentScore
-
 			}
-
 		}
-
 	}
 
-
-
-	// 2. Override with Component config (Highest priority: User manual override)
-
-	for k, v := range compConfig.Env {
-
-		resolved.Env[k] = v
-
+	// 2. Pinned scripts take the named preset's definition regardless of role priority.
+	// Project-wide pins only apply to components using the preset.
+	for script, tName := range m.ProjectConfig.Resolution.Scripts {
+		if cmd, ok := scriptsByPreset[tName][script]; ok {
+			resolved.Scripts[script] = cmd
+			resolved.ScriptSources[script] = tName
+		}
+	}
+	if compConfig.Resolution != nil {
+		for script, tName := range compConfig.Resolution.Scripts {
+			presetScripts, ok := scriptsByPreset[tName]
+			if !ok {
+				return nil, fmt.Errorf("component %q: script %q is pinned to preset %q, which the component does not use", name, script, tName)
+			}
+			cmd, ok := presetScripts[script]
+			if !ok {
+				return nil, fmt.Errorf("component %q: script %q is pinned to preset %q, which does not define it", name, script, tName)
+			}
+			resolved.Scripts[script] = cmd
+			resolved.ScriptSources[script] = tName
+		}
 	}
 
-	for k, v := range compConfig.Scripts {
-
+	// 3. Override with Component config (Highest priority: User manual override)
+	componentScripts, componentEnv, err := m.activeConfig(compConfig.Scripts, compConfig.Env, compConfig.Overrides, compConfig, typeNames)
+	if err != nil {
+		return nil, fmt.Errorf("component %q: %w", name, err)
+	}
+	config.MergeEnv(resolved.Env, componentEnv)
+	resolved.RequiredEnv = config.MergeRequiredEnv(resolved.RequiredEnv, compConfig.RequiredEnv...)
+	for k, v := range componentScripts {
 		resolved.Scripts[k] = v
-
+		resolved.ScriptSources[k] = "component"
+		if isHookName(k) {
+			resolved.Hooks[k] = []config.Script{v}
+		}
+	}
+	// pre<x>/post<x> are only hooks when script x exists
+	for hook := range resolved.Hooks {
+		if _, ok := resolved.Scripts[hookTarget(hook)]; !ok {
+			delete(resolved.Hooks, hook)
+		}
+	}
+	for _, f := range compConfig.EnvFiles {
+		resolved.EnvFiles = append(resolved.EnvFiles, absPath(resolved.AbsPath, f))
+	}
+	if err := applyParams(resolved, compConfig.Params); err != nil {
+		return nil, err
 	}
-
-
 
 	return resolved, nil
-
 }
 
-
-
 func (m *Manager) ListComponents() []string {
-
 	names := make([]string, len(m.ProjectConfig.Components))
-
 	for i, c := range m.ProjectConfig.Components {
-
 		names[i] = c.Name
-
 	}
-
 	return names
-
 }
 
-
-
 // AddDependency adds a package to the component's dependency list and saves the config.
-
 // It also updates the manifest file if applicable.
-
 func (m *Manager) AddDependency(compName, pkgName string) error {
-
 	// 1. Find component
-
 	var comp *config.ComponentConfig
-
 	for i := range m.ProjectConfig.Components {
-
 		if m.ProjectConfig.Components[i].Name == compName {
-
 			comp = &m.ProjectConfig.Components[i]
-
 			break
-
 		}
-
 	}
-
 	if comp == nil {
-
 		return fmt.Errorf("component %q not found", compName)
-
 	}
 
-
-
 	// 2. Add if not exists
-
 	exists := false
-
 	for _, d := range comp.Dependencies {
-
 		if d == pkgName {
-
 			exists = true
-
 			break
-
 		}
-
 	}
-
 	if !exists {
-
 		comp.Dependencies = append(comp.Dependencies, pkgName)
-
 	} else {
-
 		// Already exists, just ensure manifest is up to date
-
 	}
 
-
-
 	// 3. Save Config
-
-	configPath := filepath.Join(m.ProjectDir, "mngproj.toml")
-
-	if err := config.SaveProjectConfig(configPath, m.ProjectConfig); err != nil {
-
-		return fmt.Errorf("failed to save project config: %w", err)
-
+	if err := m.saveDependencies(comp); err != nil {
+		return err
 	}
 
-
-
 	// 4. Update Manifest
-
 	return m.GenerateManifest(compName)
-
 }
 
+// saveDependencies writes the component's dependencies back to the file that defines it:
+// the local override when it sets them, its component fragment for discovered components,
+// the project config otherwise.
+func (m *Manager) saveDependencies(comp *config.ComponentConfig) error {
+	if saved, err := m.saveLocalDependencies(comp); saved || err != nil {
+		return err
+	}
+	if comp.Origin != "" {
+		// Re-read the fragment so the derived name and path are not written back.
+		// Detected components get a fragment pinning their detected types.
+		fragment, err := config.LoadComponentFragment(comp.Origin)
+		if errors.Is(err, fs.ErrNotExist) {
+			fragment, err = &config.ComponentConfig{Types: comp.Types}, nil
+		}
+		if err != nil {
+			return err
+		}
+		fragment.Dependencies = comp.Dependencies
+		if err := config.SaveComponentFragment(comp.Origin, fragment); err != nil {
+			return fmt.Errorf("failed to save component file: %w", err)
+		}
+		return nil
+	}
 
+	configPath := m.ConfigPath
+	if configPath == "" {
+		configPath = filepath.Join(m.ProjectDir, "mngproj.toml")
+	}
+	// Re-read the config so discovered components are not written and
+	// components filtered out by `when` are not lost
+	cfg, err := config.LoadProjectConfig(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		declared := *m.ProjectConfig
+		declared.Components = nil
+		for _, c := range m.ProjectConfig.Components {
+			if c.Origin == "" {
+				declared.Components = append(declared.Components, c)
+			}
+		}
+		cfg, err = &declared, nil
+	}
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range cfg.Components {
+		if cfg.Components[i].Name == comp.Name {
+			cfg.Components[i].Dependencies = comp.Dependencies
+			found = true
+		}
+	}
+	if !found {
+		cfg.Components = append(cfg.Components, *comp)
+	}
+	if err := config.SaveProjectConfig(configPath, cfg); err != nil {
+		return fmt.Errorf("failed to save project config: %w", err)
+	}
+	return nil
+}
 
 // GenerateManifest writes the dependencies to the manifest file (e.g. requirements.txt)
-
 func (m *Manager) GenerateManifest(compName string) error {
-
 	resolved, err := m.ResolveComponent(compName)
-
 	if err != nil {
-
 		return err
-
 	}
 
-
-
 	if resolved.ManifestFile == "" {
-
 		return nil // No manifest to generate
-
 	}
 
-
-
 	// Find component config to get dependencies
-
 	var comp *config.ComponentConfig
-
 	for i := range m.ProjectConfig.Components {
-
 		if m.ProjectConfig.Components[i].Name == compName {
-
 			comp = &m.ProjectConfig.Components[i]
-
 			break
-
 		}
-
 	}
 
-
-
 	if len(comp.Dependencies) == 0 {
-
 		return nil
-
 	}
 
-
-
 	// Prepare content (simple newline separated for now - works for requirements.txt)
-
 	// TODO: Support other formats based on file extension or preset
-
 	content := ""
-
 	for _, dep := range comp.Dependencies {
-
 		content += dep + "\n"
-
 	}
 
-
-
 	manifestPath := filepath.Join(resolved.AbsPath, resolved.ManifestFile)
-
 	if err := os.WriteFile(manifestPath, []byte(content), 0644); err != nil {
-
 		return fmt.Errorf("failed to write manifest file %s: %w", manifestPath, err)
-
 	}
 
-
-
 	return nil
-
 }
 
-
-
 // SyncComponent generates the manifest and runs the install script
-
 func (m *Manager) SyncComponent(compName string) error {
-
 	// 1. Generate Manifest
-
 	if err := m.GenerateManifest(compName); err != nil {
-
 		return fmt.Errorf("failed to generate manifest: %w", err)
-
 	}
+	// 2. Execute 'install' script
+	return m.ExecuteScript(compName, "install", nil, nil, nil)
+}
 
-
-
-				// 2. Execute 'install' script
-
-
-
-				return m.ExecuteScript(compName, "install", nil, nil, nil)
-
-
-
+// ListComponentsByGroup returns a list of component names that belong to the specified group
+func (m *Manager) ListComponentsByGroup(group string) []string {
+	var names []string
+	for _, c := range m.ProjectConfig.Components {
+		for _, g := range c.Groups {
+			if g == group {
+				names = append(names, c.Name)
+				break
 			}
+		}
+	}
+	return names
+}
 
-
-
-			
-
-
-
-			// ListComponentsByGroup returns a list of component names that belong to the specified group
-
-
-
-			
-
-
-
-			func (m *Manager) ListComponentsByGroup(group string) []string {
-
-
-
-			
-
-
-
-				var names []string
-
-
-
-			
-
-
-
-				for _, c := range m.ProjectConfig.Components {
-
-
-
-			
-
-
-
-					for _, g := range c.Groups {
-
-
-
-			
-
-
-
-						if g == group {
-
-
-
-			
-
-
-
-							names = append(names, c.Name)
-
-
-
-			
-
-
-
-							break
-
-
-
-			
-
-
-
-						}
-
-
-
-			
-
-
-
-					}
-
-
-
-			
-
-
-
-				}
-
-
-
-			
-
-
-
-				return names
-
-
-
-			
-
-
-
+// ValidateTools checks if all required tools defined in presets are available in PATH
+func (m *Manager) ValidateTools() error {
+	checked := make(map[string]bool)
+	for i := range m.ProjectConfig.Components {
+		// Failing on unloadable or conflicting presets is safer to ensure environment consistency.
+		types, presets, err := m.expandTypes(&m.ProjectConfig.Components[i])
+		if err != nil {
+			return fmt.Errorf("tool validation failed: %w", err)
+		}
+		for _, tName := range types {
+			if checked[tName] {
+				continue
 			}
-
-
-
-			
-
-
-
-			
-
-
-
-			
-
-
-
-			// ValidateTools checks if all required tools defined in presets are available in PATH
-
-
-
-			
-
-
-
-			func (m *Manager) ValidateTools() error {
-
-
-
-			
-
-
-
-				checked := make(map[string]bool)
-
-
-
-			
-
-
-
-				for _, comp := range m.ProjectConfig.Components {
-
-
-
-			
-
-
-
-					types := comp.Types
-
-
-
-			
-
-
-
-					if len(types) == 0 && comp.Type != "" {
-
-
-
-			
-
-
-
-						types = []string{comp.Type}
-
-
-
-			
-
-
-
-					}
-
-
-
-			
-
-
-
-			
-
-
-
-			
-
-
-
-					for _, tName := range types {
-
-
-
-			
-
-
-
-						if checked[tName] {
-
-
-
-			
-
-
-
-							continue
-
-
-
-			
-
-
-
-						}
-
-
-
-			
-
-
-
-						preset, err := config.LoadPreset(m.PresetsDir, tName)
-
-
-
-			
-
-
-
-						if err != nil {
-
-
-
-			
-
-
-
-							// Warn but continue? Or fail?
-
-
-
-			
-
-
-
-							// Failing is safer to ensure environment consistency.
-
-
-
-			
-
-
-
-							return fmt.Errorf("failed to load preset %q for tool validation: %w", tName, err)
-
-
-
-			
-
-
-
-						}
-
-
-
-			
-
-
-
-			
-
-
-
-			
-
-
-
-						for _, tool := range preset.Metadata.RequiredTools {
-
-
-
-			
-
-
-
-							if _, err := exec.LookPath(tool); err != nil {
-
-
-
-			
-
-
-
-								return fmt.Errorf("required tool %q (from preset %q) not found in PATH", tool, tName)
-
-
-
-			
-
-
-
-							}
-
-
-
-			
-
-
-
-						}
-
-
-
-			
-
-
-
-						checked[tName] = true
-
-
-
-			
-
-
-
-					}
-
-
-
-			
-
-
-
+			preset := presets[tName]
+			for _, tool := range preset.Metadata.RequiredTools {
+				if _, err := exec.LookPath(tool); err != nil {
+					return fmt.Errorf("required tool %q (from preset %q) not found in PATH", tool, tName)
 				}
-
-
-
-			
-
-
-
-				return nil
-
-
-
-			
-
-
-
 			}
-
-
-
-			
-
-
-
-			
-
-
-
-			
-
-
-
-		
-
-
-
-	
+			checked[tName] = true
+		}
+	}
+	return nil
+}
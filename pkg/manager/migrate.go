@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mngproj/pkg/config"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// MigrationChange describes the migration steps that changed one file
+type MigrationChange struct {
+	Path  string
+	Steps []string
+}
+
+// Migrate brings the project's config files to config.CurrentSchema: the project
+// config, the local override, component fragments and the presets/ directory next to
+// the config. Only migrations newer than the project's schema run. With write unset
+// nothing is saved, which lets callers check whether a migration is pending.
+func (m *Manager) Migrate(write bool) ([]MigrationChange, error) {
+	from := config.SchemaOf(m.ProjectConfig)
+	var steps []config.Migration
+	for _, mig := range config.Migrations {
+		if mig.Version > from {
+			steps = append(steps, mig)
+		}
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	var changes []MigrationChange
+	migrate := func(path string, kind config.FileKind) error {
+		isConfig := path == m.ConfigPath
+		applied, data, err := config.MigrateFile(path, kind, steps, isConfig)
+		if err != nil {
+			return err
+		}
+		if isConfig {
+			applied = append(applied, fmt.Sprintf("schema %d -> %d", from, config.CurrentSchema))
+		}
+		if data == nil {
+			return nil
+		}
+		changes = append(changes, MigrationChange{Path: path, Steps: applied})
+		if !write {
+			return nil
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	}
+
+	if err := migrate(m.ConfigPath, config.ProjectFile); err != nil {
+		return nil, err
+	}
+	if m.LocalPath != "" {
+		if err := migrate(m.LocalPath, config.LocalFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, comp := range m.allComponents {
+		if comp.Origin == "" {
+			continue
+		}
+		// Detected components have no fragment until dependencies are saved
+		if _, err := os.Stat(comp.Origin); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := migrate(comp.Origin, config.FragmentFile); err != nil {
+			return nil, err
+		}
+	}
+
+	presetsDir := filepath.Join(m.configDir(), "presets")
+	err := filepath.WalkDir(presetsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == presetsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !slices.Contains(config.PresetExtensions, filepath.Ext(path)) {
+			return nil
+		}
+		return migrate(path, config.PresetFile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/utils"
+	"os"
+)
+
+// LogWriter returns the writer used for a component's output in aggregated logs
+// (up, watch), formatted according to the user's color and log_format settings
+func (m *Manager) LogWriter(compName string) *utils.PrefixWriter {
+	pw := &utils.PrefixWriter{Prefix: compName, Writer: os.Stdout, Redactor: m.Redactor()}
+	if m.settings().LogFormat == "json" {
+		pw.JSON = true
+	} else if m.colorEnabled() {
+		pw.Color = utils.PrefixColor(compName)
+	}
+	return pw
+}
+
+// colorEnabled applies the color setting; auto colors terminals unless NO_COLOR is set
+func (m *Manager) colorEnabled() bool {
+	switch m.settings().Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// settings returns the user config, which is empty for managers not created by New
+func (m *Manager) settings() *config.GlobalConfig {
+	if m.Global == nil {
+		return &config.GlobalConfig{}
+	}
+	return m.Global
+}
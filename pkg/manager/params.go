@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// applyParams validates component overrides against the parameters declared by
+// the component's presets, then renders string values as templates ({{.Name}}).
+func applyParams(resolved *ResolvedComponent, overrides map[string]any) error {
+	for name, value := range overrides {
+		def, ok := resolved.Params[name]
+		if !ok {
+			declared := slices.Sorted(maps.Keys(resolved.Params))
+			return fmt.Errorf("component %q: unknown param %q (declared: %s)", resolved.Name, name, strings.Join(declared, ", "))
+		}
+		if paramKind(def) != paramKind(value) {
+			return fmt.Errorf("component %q: param %q must be a %s, got %s", resolved.Name, name, paramKind(def), paramKind(value))
+		}
+		resolved.Params[name] = value
+	}
+
+	for name, value := range resolved.Params {
+		str, ok := value.(string)
+		if !ok || !strings.Contains(str, "{{") {
+			continue
+		}
+		rendered, err := renderTemplate(str, ScriptContext{Name: resolved.Name})
+		if err != nil {
+			return fmt.Errorf("component %q: param %q: %w", resolved.Name, name, err)
+		}
+		resolved.Params[name] = rendered
+	}
+	return nil
+}
+
+// paramKind maps a decoded config value to the param type it represents
+func paramKind(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int64, float64:
+		return "number"
+	case []any:
+		return "list"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// renderTemplate executes text with ctx. The `component` function looks up another
+// component when ctx was created with a lookup, e.g. {{ (component "api").Env.PORT }}.
+func renderTemplate(text string, ctx ScriptContext) (string, error) {
+	funcs := template.FuncMap{
+		"component": func(name string) (*ComponentView, error) {
+			if ctx.lookup == nil {
+				return nil, fmt.Errorf("component %q cannot be referenced here", name)
+			}
+			return ctx.lookup(name)
+		},
+	}
+	tmpl, err := template.New("script").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.String(), nil
+}
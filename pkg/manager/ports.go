@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"fmt"
+	"maps"
+	"mngproj/pkg/config"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// autoPort is the port value that asks mngproj to pick a free port
+const autoPort = "auto"
+
+// portSpec is one named port declared by a component
+type portSpec struct {
+	component string
+	name      string
+	port      int // 0 for auto
+}
+
+// String names the port in messages, e.g. "api.http"
+func (p portSpec) String() string {
+	return p.component + "." + p.name
+}
+
+// declaredPorts parses the ports of the named components, sorted by component and name
+func (m *Manager) declaredPorts(names []string) ([]portSpec, error) {
+	var specs []portSpec
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		var comp *config.ComponentConfig
+		for i := range m.ProjectConfig.Components {
+			if m.ProjectConfig.Components[i].Name == name {
+				comp = &m.ProjectConfig.Components[i]
+				break
+			}
+		}
+		if comp == nil {
+			return nil, fmt.Errorf("component %q not found", name)
+		}
+		for _, portName := range slices.Sorted(maps.Keys(comp.Ports)) {
+			spec := portSpec{component: name, name: portName}
+			switch v := comp.Ports[portName].(type) {
+			case string:
+				if v != autoPort {
+					return nil, fmt.Errorf("port %s: expected a number or %q, got %q", spec, autoPort, v)
+				}
+			case int64:
+				spec.port = int(v)
+			case int:
+				spec.port = v
+			case float64:
+				spec.port = int(v)
+			default:
+				return nil, fmt.Errorf("port %s: expected a number or %q, got %v", spec, autoPort, v)
+			}
+			if spec.port == 0 && comp.Ports[portName] != autoPort || spec.port < 0 || spec.port > 65535 {
+				return nil, fmt.Errorf("port %s: %v is out of range", spec, comp.Ports[portName])
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// AllocatePorts assigns the ports of the named components before they are started.
+// It fails without assigning anything when two components declare the same port or
+// a fixed port is already in use on this machine. Auto ports get a free port.
+func (m *Manager) AllocatePorts(names []string) error {
+	specs, err := m.declaredPorts(names)
+	if err != nil {
+		return err
+	}
+
+	owners := make(map[int]portSpec)
+	var problems []string
+	for _, spec := range specs {
+		if spec.port == 0 {
+			continue
+		}
+		if other, ok := owners[spec.port]; ok {
+			problems = append(problems, fmt.Sprintf("port %d is declared by both %s and %s", spec.port, other, spec))
+			continue
+		}
+		owners[spec.port] = spec
+		if !portFree(spec.port) {
+			problems = append(problems, fmt.Sprintf("port %d (%s) is already in use", spec.port, spec))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("port conflicts:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	assigned := make(map[string]map[string]int)
+	for _, spec := range specs {
+		port := spec.port
+		for port == 0 {
+			if port, err = freePort(); err != nil {
+				return fmt.Errorf("port %s: %w", spec, err)
+			}
+			if _, taken := owners[port]; taken {
+				port = 0
+			}
+		}
+		owners[port] = spec
+		if assigned[spec.component] == nil {
+			assigned[spec.component] = make(map[string]int)
+		}
+		assigned[spec.component][spec.name] = port
+	}
+
+	m.portsMu.Lock()
+	defer m.portsMu.Unlock()
+	if m.ports == nil {
+		m.ports = make(map[string]map[string]int)
+	}
+	maps.Copy(m.ports, assigned)
+	return nil
+}
+
+// Ports returns the ports of every component, allocating auto ports that were not
+// assigned by AllocatePorts. Fixed ports are returned without checking them.
+func (m *Manager) Ports() (map[string]map[string]int, error) {
+	specs, err := m.declaredPorts(m.ListComponents())
+	if err != nil {
+		return nil, err
+	}
+
+	m.portsMu.Lock()
+	defer m.portsMu.Unlock()
+	if m.ports == nil {
+		m.ports = make(map[string]map[string]int)
+	}
+	for _, spec := range specs {
+		if _, ok := m.ports[spec.component][spec.name]; ok {
+			continue
+		}
+		port := spec.port
+		if port == 0 {
+			if port, err = freePort(); err != nil {
+				return nil, fmt.Errorf("port %s: %w", spec, err)
+			}
+		}
+		if m.ports[spec.component] == nil {
+			m.ports[spec.component] = make(map[string]int)
+		}
+		m.ports[spec.component][spec.name] = port
+	}
+
+	result := make(map[string]map[string]int, len(m.ports))
+	for comp, ports := range m.ports {
+		result[comp] = maps.Clone(ports)
+	}
+	return result, nil
+}
+
+// portEnv returns the port variables for comp: PORT_<NAME> for its own ports and
+// MNGPROJ_SVC_<COMP>_<NAME>_URL for the ports of every other component
+func (m *Manager) portEnv(comp string) (map[string]string, error) {
+	ports, err := m.Ports()
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for owner, named := range ports {
+		for name, port := range named {
+			if owner == comp {
+				env["PORT_"+envName(name)] = strconv.Itoa(port)
+			} else {
+				env["MNGPROJ_SVC_"+envName(owner)+"_"+envName(name)+"_URL"] = fmt.Sprintf("http://localhost:%d", port)
+			}
+		}
+	}
+	return env, nil
+}
+
+// envName turns a component or port name into an environment variable fragment
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
+// portFree reports whether port can be listened on
+func portFree(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// freePort asks the OS for an unused port
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"fmt"
+	"io/fs"
+	"mngproj/pkg/config"
+	"mngproj/presets"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// declaredTypes returns the preset types listed on a component, honouring the legacy `type` field
+func declaredTypes(comp *config.ComponentConfig) []string {
+	if len(comp.Types) == 0 && comp.Type != "" {
+		return []string{comp.Type}
+	}
+	return comp.Types
+}
+
+// EffectiveTypes expands a component's declared types with the presets they require
+// and checks declared conflicts. Required presets that are not declared explicitly are
+// inserted right before the preset requiring them.
+func (m *Manager) EffectiveTypes(comp *config.ComponentConfig) ([]string, error) {
+	types, _, err := m.expandTypes(comp)
+	return types, err
+}
+
+func (m *Manager) expandTypes(comp *config.ComponentConfig) ([]string, map[string]*config.PresetConfig, error) {
+	declared := declaredTypes(comp)
+	presets := make(map[string]*config.PresetConfig)
+	var result []string
+	var visiting []string
+
+	var add func(typeName string) error
+	add = func(typeName string) error {
+		if slices.Contains(result, typeName) {
+			return nil
+		}
+		if slices.Contains(visiting, typeName) {
+			return fmt.Errorf("preset requirement cycle involving %q", typeName)
+		}
+		visiting = append(visiting, typeName)
+		defer func() { visiting = visiting[:len(visiting)-1] }()
+
+		preset, err := m.Presets().Load(typeName)
+		if err != nil {
+			return fmt.Errorf("failed to load preset %q: %w", typeName, err)
+		}
+		presets[typeName] = preset
+
+		for _, req := range preset.Metadata.Requires {
+			// Explicitly declared presets keep the position chosen by the user
+			if slices.Contains(declared, req) {
+				continue
+			}
+			if err := add(req); err != nil {
+				return err
+			}
+		}
+		result = append(result, typeName)
+		return nil
+	}
+
+	for _, t := range declared {
+		if err := add(t); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, t := range result {
+		for _, c := range presets[t].Metadata.Conflicts {
+			if slices.Contains(result, c) {
+				return nil, nil, fmt.Errorf("component %q: preset %q conflicts with %q", comp.Name, t, c)
+			}
+		}
+	}
+
+	return result, presets, nil
+}
+
+// EjectPresets copies the embedded presets into destDir, keeping their subdirectory layout,
+// so they can be customised. When names is empty every preset is ejected.
+// Existing files are left untouched unless force is set. It returns the written paths.
+func EjectPresets(destDir string, names []string, force bool) ([]string, error) {
+	var written []string
+	matched := make(map[string]bool)
+
+	err := fs.WalkDir(presets.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".toml" {
+			return nil
+		}
+
+		typeName := strings.TrimSuffix(d.Name(), ".toml")
+		if len(names) > 0 {
+			// {type}_{GOOS}.toml variants belong to their base type
+			base, _, _ := strings.Cut(typeName, "_")
+			switch {
+			case slices.Contains(names, typeName):
+				matched[typeName] = true
+			case slices.Contains(names, base):
+				matched[base] = true
+			default:
+				return nil
+			}
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(path))
+		if _, err := os.Stat(target); err == nil && !force {
+			fmt.Printf("Skipping %s (already exists, use --force to overwrite)\n", target)
+			return nil
+		}
+
+		data, err := fs.ReadFile(presets.FS, path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		written = append(written, target)
+		return nil
+	})
+	if err != nil {
+		return written, fmt.Errorf("failed to eject presets: %w", err)
+	}
+
+	for _, name := range names {
+		if !matched[name] {
+			return written, fmt.Errorf("no embedded preset named %q", name)
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package manager
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd; its children are left to exit with it
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
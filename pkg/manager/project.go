@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"fmt"
+	"mngproj/pkg/config"
+	"slices"
+)
+
+// ProjectScope is the component name under which [project.scripts] run. It scopes
+// their secrets and labels their output. Steps refer to them as @script from other
+// project scripts, or @:script from components. The name is only reserved in
+// projects that declare project scripts.
+const ProjectScope = "project"
+
+// ProjectScripts returns the project scripts as a component rooted at ProjectDir,
+// so that they run with the same machinery as component scripts
+func (m *Manager) ProjectScripts() (*ResolvedComponent, error) {
+	project := m.ProjectConfig.Project
+	// `when` conditions see the project as a component without types
+	facts := &config.ComponentConfig{Name: ProjectScope, Path: "."}
+	scripts, env, err := m.activeConfig(project.Scripts, project.Env, nil, facts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	comp := &ResolvedComponent{
+		Name:    ProjectScope,
+		AbsPath: m.ProjectDir,
+		Env:     env,
+		Scripts: scripts,
+		Params:  make(map[string]any),
+
+		ScriptSources: make(map[string]string),
+		Hooks:         make(map[string][]config.Script),
+	}
+	for name, script := range comp.Scripts {
+		comp.ScriptSources[name] = "project"
+		if isHookName(name) {
+			if _, ok := comp.Scripts[hookTarget(name)]; ok {
+				comp.Hooks[name] = []config.Script{script}
+			}
+		}
+	}
+	return comp, nil
+}
+
+// HasProjectScript reports whether [project.scripts] defines name and its `when`
+// condition holds
+func (m *Manager) HasProjectScript(name string) (bool, error) {
+	if _, ok := m.ProjectConfig.Project.Scripts[name]; !ok {
+		return false, nil
+	}
+	comp, err := m.ProjectScripts()
+	if err != nil {
+		return false, err
+	}
+	_, ok := comp.Scripts[name]
+	return ok, nil
+}
+
+// isProjectScope reports whether name refers to the project scripts rather than to
+// a component. A component may only be named after the scope in projects without
+// project scripts, see checkReservedNames.
+func (m *Manager) isProjectScope(name string) bool {
+	return name == ProjectScope && !slices.Contains(m.ListComponents(), ProjectScope)
+}
+
+// checkReservedNames rejects components that would share the project scope
+func (m *Manager) checkReservedNames() error {
+	if len(m.ProjectConfig.Project.Scripts) == 0 {
+		return nil
+	}
+	for _, c := range m.ProjectConfig.Components {
+		if c.Name == ProjectScope {
+			return fmt.Errorf("component name %q is reserved for project scripts", ProjectScope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// scriptRef is a step of the form @script or @component:script, optionally followed by arguments
+type scriptRef struct {
+	component string
+	script    string
+	args      []string
+}
+
+// parseRef parses a reference step of comp. @component:script always references a
+// script, and @:script a project script. A bare @script only references a script of
+// comp when comp defines one by that name; otherwise the step is a shell command, so
+// that commands like `@echo off` keep working.
+func parseRef(comp *ResolvedComponent, step string) (scriptRef, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(step), "@")
+	if !ok || rest == "" {
+		return scriptRef{}, false
+	}
+	fields := strings.Fields(rest)
+	ref := scriptRef{component: comp.Name, script: fields[0], args: fields[1:]}
+	if target, script, found := strings.Cut(fields[0], ":"); found {
+		ref.component, ref.script = target, script
+		if target == "" {
+			ref.component = ProjectScope
+		}
+		return ref, true
+	}
+	if _, ok := comp.Scripts[ref.script]; !ok {
+		return scriptRef{}, false
+	}
+	return ref, true
+}
+
+// invocation tracks the scripts entered while running one top-level script,
+// to detect reference cycles and run each distinct reference once
+type invocation struct {
+	stack []string
+	done  map[string]bool
+}
+
+func newInvocation() *invocation {
+	return &invocation{done: make(map[string]bool)}
+}
+
+// enter records that component:script is running and returns a function that
+// removes it again. Entering a script that is already running is a cycle.
+func (inv *invocation) enter(component, script string) (func(), error) {
+	key := component + ":" + script
+	if slices.Contains(inv.stack, key) {
+		return nil, fmt.Errorf("script reference cycle: %s -> %s", strings.Join(inv.stack, " -> "), key)
+	}
+	inv.stack = append(inv.stack, key)
+	return func() { inv.stack = inv.stack[:len(inv.stack)-1] }, nil
+}
+
+// runRef runs a referenced script, skipping references that already completed in this invocation
+func (m *Manager) runRef(inv *invocation, ref scriptRef, args []string, stdout, stderr io.Writer) error {
+	key := strings.Join(append([]string{ref.component + ":" + ref.script}, args...), " ")
+	if inv.done[key] {
+		return nil
+	}
+	if err := m.execute(inv, ref.component, ref.script, args, stdout, stderr); err != nil {
+		return err
+	}
+	inv.done[key] = true
+	return nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvProblem is a required variable that is missing or does not match its pattern
+type EnvProblem struct {
+	Component   string
+	Name        string
+	Reason      string // e.g. "is not set"
+	Description string
+}
+
+func (p EnvProblem) String() string {
+	s := fmt.Sprintf("%s: %s %s", p.Component, p.Name, p.Reason)
+	if p.Description != "" {
+		s += " (" + p.Description + ")"
+	}
+	return s
+}
+
+// CheckRequiredEnv checks the required env of the named components against the
+// environment their scripts would get. script selects whose own env is included;
+// with "" or a script a component lacks, only the component env is used.
+// Problems are collected across all components; errors are reserved for invalid
+// patterns and env values that cannot be computed.
+func (m *Manager) CheckRequiredEnv(script string, names ...string) ([]EnvProblem, error) {
+	var problems []EnvProblem
+	for _, name := range names {
+		comp, err := m.ResolveComponent(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(comp.RequiredEnv) == 0 {
+			continue
+		}
+		env, err := m.prepareEnv(comp, comp.Scripts[script].Env)
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %w", name, err)
+		}
+		found, err := envProblems(comp, env)
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %w", name, err)
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// checkScriptEnv fails with every unmet requirement of comp against env, the
+// environment a script is about to run with
+func checkScriptEnv(comp *ResolvedComponent, env map[string]string) error {
+	problems, err := envProblems(comp, env)
+	if err != nil {
+		return fmt.Errorf("component %q: %w", comp.Name, err)
+	}
+	return RequiredEnvError(problems)
+}
+
+// envProblems checks the requirements of comp against env on top of the process environment
+func envProblems(comp *ResolvedComponent, env map[string]string) ([]EnvProblem, error) {
+	var problems []EnvProblem
+	for _, req := range comp.RequiredEnv {
+		value, ok := env[req.Name]
+		if !ok {
+			value, ok = os.LookupEnv(req.Name)
+		}
+		reason, err := req.Check(value, ok)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			problems = append(problems, EnvProblem{Component: comp.Name, Name: req.Name, Reason: reason, Description: req.Description})
+		}
+	}
+	return problems, nil
+}
+
+// RequiredEnvError combines problems into a single error, or returns nil when there are none
+func RequiredEnvError(problems []EnvProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.String()
+	}
+	return fmt.Errorf("missing or invalid environment variables:\n%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"mngproj/pkg/secrets"
+	"mngproj/pkg/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretsPath returns the location of the encrypted secrets file
+func (m *Manager) SecretsPath() string {
+	return filepath.Join(m.configDir(), secrets.FileName)
+}
+
+// OpenSecrets decrypts the project's secrets store. With createKey, a missing key
+// is generated into the key file, which is also added to .gitignore.
+func (m *Manager) OpenSecrets(createKey bool) (*secrets.Store, error) {
+	key, err := secrets.LoadKey(m.configDir())
+	if errors.Is(err, secrets.ErrNoKey) && createKey {
+		if key, err = secrets.GenerateKeyFile(m.configDir()); err == nil {
+			fmt.Printf("Generated new secrets key in %s (keep it out of version control)\n", filepath.Join(m.configDir(), secrets.KeyFileName))
+			err = ensureGitignored(m.configDir(), secrets.KeyFileName)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Open(m.SecretsPath(), key)
+}
+
+// Redactor returns the redactor holding every secret value resolved so far.
+// Output that may contain secrets (command echo, multiplexed logs) goes through it.
+func (m *Manager) Redactor() *utils.Redactor {
+	return &m.redactor
+}
+
+// secretValue resolves a "secret:name" env reference of a component and registers
+// the value with the redactor
+func (m *Manager) secretValue(component, name string) (string, error) {
+	m.secretsMu.Lock()
+	defer m.secretsMu.Unlock()
+
+	if m.secretStore == nil {
+		store, err := m.OpenSecrets(false)
+		if err != nil {
+			return "", fmt.Errorf("failed to open secrets: %w", err)
+		}
+		m.secretStore = store
+	}
+	value, ok := m.secretStore.Get(component, name)
+	if !ok {
+		return "", fmt.Errorf("secret %q is not set for component %q (use: mngproj secrets set %s %s)", name, component, component, name)
+	}
+	m.redactor.Add(value)
+	return value, nil
+}
+
+// ensureGitignored appends pattern to dir/.gitignore unless it is already listed
+func ensureGitignored(dir, pattern string) error {
+	path := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content+pattern+"\n"), 0644)
+}
@@ -3,12 +3,9 @@ package manager
 import (
 	"fmt"
 	"log"
-	"mngproj/pkg/utils"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 )
 
@@ -22,7 +19,7 @@ func (m *Manager) WatchComponent(compName string) {
 	root := comp.AbsPath
 	fmt.Printf("[%s] Watching %s for changes...\n", compName, root)
 
-	var currentCmd *exec.Cmd
+	var currentCmd *ScriptProcess
 	var lastModTime time.Time
 
 	restart := make(chan bool, 1)
@@ -64,13 +61,12 @@ func (m *Manager) WatchComponent(compName string) {
 
 	for range restart {
 		if currentCmd != nil && currentCmd.Process != nil {
-			// Try to kill process group to catch children
-			syscall.Kill(-currentCmd.Process.Pid, syscall.SIGKILL)
-			currentCmd.Process.Kill()
+			// Kill the process group to catch children
+			killProcessGroup(currentCmd.Cmd)
 			currentCmd.Wait()
 		}
 
-		pw := &utils.PrefixWriter{Prefix: compName, Writer: os.Stdout}
+		pw := m.LogWriter(compName)
 		// Pass SysProcAttr to set process group for group kill support
 		// Note: ExecuteScriptAsync creates the cmd, we need to modify it inside if possible.
 		// Current API doesn't allow modifying cmd before Start.
@@ -79,6 +75,7 @@ func (m *Manager) WatchComponent(compName string) {
 		cmd, err := m.ExecuteScriptAsync(compName, "run", nil, pw, pw)
 		if err != nil {
 			fmt.Fprintf(pw, "Start Error: %v\n", err)
+			pw.Close()
 			currentCmd = nil
 		} else {
 			// Set process group ID so we can kill children if needed
@@ -89,6 +86,7 @@ func (m *Manager) WatchComponent(compName string) {
 			currentCmd = cmd
 			go func() {
 				cmd.Wait()
+				pw.Close()
 			}()
 		}
 	}
@@ -0,0 +1,197 @@
+// Package secrets implements the encrypted per-component secrets file of a project.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// FileName is the encrypted store, meant to be committed next to mngproj.toml
+	FileName = "mngproj.secrets"
+	// KeyFileName is the local key file next to mngproj.toml. It must never be committed.
+	KeyFileName = ".mngproj.key"
+	// KeyEnvVar holds the base64 encoded key and takes precedence over the key file
+	KeyEnvVar = "MNGPROJ_SECRETS_KEY"
+	// RefPrefix marks env values that reference a secret, e.g. "secret:db_password"
+	RefPrefix = "secret:"
+)
+
+const keySize = 32 // AES-256
+
+// ErrNoKey is returned when neither MNGPROJ_SECRETS_KEY nor the key file is available
+var ErrNoKey = errors.New("no secrets key: set " + KeyEnvVar + " or create " + KeyFileName)
+
+// envelope is the on-disk format of the store
+type envelope struct {
+	Version    int    `json:"version"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Store holds decrypted secrets, keyed by component then secret name
+type Store struct {
+	path string
+	key  []byte
+	data map[string]map[string]string
+}
+
+// LoadKey returns the key from MNGPROJ_SECRETS_KEY or from the key file in dir
+func LoadKey(dir string) ([]byte, error) {
+	encoded := os.Getenv(KeyEnvVar)
+	source := KeyEnvVar
+	if encoded == "" {
+		data, err := os.ReadFile(filepath.Join(dir, KeyFileName))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoKey
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		encoded = string(data)
+		source = filepath.Join(dir, KeyFileName)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid secrets key in %s: expected %d base64 encoded bytes", source, keySize)
+	}
+	return key, nil
+}
+
+// GenerateKeyFile creates a new random key file in dir and returns the key
+func GenerateKeyFile(dir string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	path := filepath.Join(dir, KeyFileName)
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+// Open decrypts the store at path. A missing file yields an empty store.
+func Open(path string, key []byte) (*Store, error) {
+	s := &Store{path: path, key: key, data: make(map[string]map[string]string)}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file (wrong key?)")
+	}
+	if err := json.Unmarshal(plaintext, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets: %w", err)
+	}
+	return s, nil
+}
+
+// Save encrypts the store with a fresh nonce and writes it back
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	aead, err := newAEAD(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out, err := json.MarshalIndent(envelope{
+		Version:    1,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, append(out, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// Get returns a secret of a component
+func (s *Store) Get(component, name string) (string, bool) {
+	v, ok := s.data[component][name]
+	return v, ok
+}
+
+// Set stores a secret of a component
+func (s *Store) Set(component, name, value string) {
+	if s.data[component] == nil {
+		s.data[component] = make(map[string]string)
+	}
+	s.data[component][name] = value
+}
+
+// Delete removes a secret and reports whether it existed
+func (s *Store) Delete(component, name string) bool {
+	if _, ok := s.data[component][name]; !ok {
+		return false
+	}
+	delete(s.data[component], name)
+	if len(s.data[component]) == 0 {
+		delete(s.data, component)
+	}
+	return true
+}
+
+// Names returns the sorted secret names of a component
+func (s *Store) Names(component string) []string {
+	return slices.Sorted(maps.Keys(s.data[component]))
+}
+
+// Components returns the sorted names of components holding secrets
+func (s *Store) Components() []string {
+	return slices.Sorted(maps.Keys(s.data))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
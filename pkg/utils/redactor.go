@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redactor masks registered secret values in text. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   []string
+	known    map[string]bool // The entries of values
+	replacer *strings.Replacer
+}
+
+const redactedMask = "******"
+
+// Add registers values that must never be printed. Values that are already
+// registered are ignored, so secrets may be added each time they are resolved.
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := false
+	for _, v := range values {
+		if v == "" || r.known[v] {
+			continue
+		}
+		if r.known == nil {
+			r.known = make(map[string]bool)
+		}
+		r.known[v] = true
+		r.values = append(r.values, v)
+		added = true
+	}
+	if !added {
+		return
+	}
+	// Longer values first so a secret containing another one is masked as a whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	pairs := make([]string, 0, len(r.values)*2)
+	for _, v := range r.values {
+		pairs = append(pairs, v, redactedMask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact returns s with every registered value replaced by a mask
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// partialStart returns the offset of the longest suffix of s that is the beginning,
+// but not the whole, of a registered value, or len(s) when there is none
+func (r *Redactor) partialStart(s string) int {
+	if r == nil {
+		return len(s)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	start := len(s)
+	for _, v := range r.values {
+		for k := min(len(v)-1, len(s)); k > 0 && len(s)-k < start; k-- {
+			if strings.HasSuffix(s, v[:k]) {
+				start = len(s) - k
+				break
+			}
+		}
+	}
+	return start
+}
@@ -1,25 +1,79 @@
 package utils
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 )
 
-// PrefixWriter prefixes each line with a tag
+// PrefixWriter prefixes each line with a tag. Output is buffered until a line is
+// complete, so that lines and secrets split across writes are formatted and masked
+// as a whole; Close writes what is left.
 type PrefixWriter struct {
-	Prefix string
-	Writer io.Writer
+	Prefix   string
+	Writer   io.Writer
+	Redactor *Redactor // Optional: masks secret values in each line
+	Color    string    // Optional ANSI color code for the tag, e.g. "36"
+	JSON     bool      // Write each line as {"component": Prefix, "line": ...} instead
+
+	mu      sync.Mutex
+	pending string // Output after the last complete line; its start may already be redacted
 }
 
 func (w *PrefixWriter) Write(p []byte) (n int, err error) {
-	lines := bytes.Split(p, []byte("\n"))
-	for i, line := range lines {
-		if len(line) == 0 && i == len(lines)-1 {
-			continue
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	text := w.pending + string(p)
+	// A secret may continue in the next write; keep its beginning unredacted
+	cut := w.Redactor.partialStart(text)
+	redacted := w.Redactor.Redact(text[:cut])
+	last := strings.LastIndexByte(redacted, '\n')
+	w.pending = redacted[last+1:] + text[cut:]
+	if last >= 0 {
+		w.writeLines(redacted[:last])
+	}
+	return len(p), nil
+}
+
+// Close writes the incomplete last line, if any
+func (w *PrefixWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending != "" {
+		w.writeLines(w.Redactor.Redact(w.pending))
+		w.pending = ""
+	}
+	return nil
+}
+
+// writeLines writes each line of text, which is already redacted, with the tag
+func (w *PrefixWriter) writeLines(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		var out string
+		switch {
+		case w.JSON:
+			data, _ := json.Marshal(map[string]string{"component": w.Prefix, "line": line})
+			out = string(data) + "\n"
+		case w.Color != "":
+			out = fmt.Sprintf("\033[%sm[%s]\033[0m %s\n", w.Color, w.Prefix, line)
+		default:
+			out = fmt.Sprintf("[%s] %s\n", w.Prefix, line)
 		}
-		out := fmt.Sprintf("[%s] %s\n", w.Prefix, string(line))
 		w.Writer.Write([]byte(out))
 	}
-	return len(p), nil
+}
+
+// prefixColors are the ANSI colors cycled through for component tags
+var prefixColors = []string{"36", "33", "32", "35", "34", "31"}
+
+// PrefixColor picks a stable color for a tag
+func PrefixColor(prefix string) string {
+	var sum int
+	for _, c := range prefix {
+		sum += int(c)
+	}
+	return prefixColors[sum%len(prefixColors)]
 }
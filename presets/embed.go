@@ -0,0 +1,9 @@
+// Package presets embeds the built-in preset definitions shipped with mngproj.
+package presets
+
+import "embed"
+
+// FS holds the preset tree (languages/, frameworks/, managers/, tools/) compiled into the binary
+//
+//go:embed languages frameworks managers tools
+var FS embed.FS
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrossComponentReferences(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "api"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+[env]
+PORT = "8000"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "crossref"
+
+[[components]]
+name = "api"
+type = "lang"
+path = "api"
+[components.env]
+PORT = "9000"
+
+[[components]]
+name = "web"
+type = "lang"
+[components.env]
+API_URL = "http://localhost:${components.api.env.PORT}"
+API_DIR = "${components.api.path}"
+[components.scripts]
+show = "echo $API_URL {{ (component \"api\").Env.PORT }} $(basename $API_DIR)"
+
+[[components]]
+name = "a"
+type = "lang"
+[components.env]
+X = "${components.b.env.Y}"
+[components.scripts]
+show = "echo $X"
+
+[[components]]
+name = "b"
+type = "lang"
+[components.env]
+Y = "${components.a.env.X}"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("web", "show", nil, &stdout, nil); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "http://localhost:9000 9000 api" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	err = mgr.ExecuteScript("a", "show", nil, &bytes.Buffer{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "component reference cycle: a -> b -> a") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectComponents(t *testing.T) {
+	t.Setenv("MNGPROJ_PRESETS_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "services", "api"), 0755)
+	os.MkdirAll(filepath.Join(root, "web", "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(root, "services", "api", "go.mod"), []byte("module api\n"), 0644)
+	os.WriteFile(filepath.Join(root, "services", "api", "Dockerfile"), []byte("FROM scratch\n"), 0644)
+	os.WriteFile(filepath.Join(root, "web", "package.json"), []byte(`{"dependencies": {"next": "14.0.0"}}`), 0644)
+	os.WriteFile(filepath.Join(root, "web", "node_modules", "dep", "package.json"), []byte(`{}`), 0644)
+
+	detector, err := manager.NewDetector(config.NewPresetRegistry(manager.PresetSearchPath(root)), nil, nil)
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	comps, err := detector.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var got []string
+	for _, c := range comps {
+		got = append(got, c.Name+"@"+c.Path+"="+strings.Join(c.Types, ","))
+	}
+	want := []string{"api@services/api=go,docker", "web@web=node,nextjs"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+
+	// Writing creates mngproj.toml, and a second run adds nothing
+	added, err := manager.WriteDetectedComponents(root, comps)
+	if err != nil || len(added) != 2 {
+		t.Fatalf("WriteDetectedComponents failed: %v (%v)", err, added)
+	}
+	mgr, err := manager.New(root)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	comp, err := mgr.ResolveComponent("web")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Scripts["run"].Cmd != "npm run dev" {
+		t.Errorf("Expected nextjs run script, got %q", comp.Scripts["run"].Cmd)
+	}
+	if added, err := manager.WriteDetectedComponents(root, comps); err != nil || len(added) != 0 {
+		t.Errorf("Expected no new components, got %v (%v)", added, err)
+	}
+}
+
+func TestDiscoveryFallsBackToDetection(t *testing.T) {
+	t.Setenv("MNGPROJ_PRESETS_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "detect"
+[discovery]
+paths = ["libs/*"]
+`), 0644)
+	os.MkdirAll(filepath.Join(projectDir, "libs", "core"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "libs", "empty"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "libs", "core", "Cargo.toml"), []byte("[package]\nname = \"core\"\n"), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if names := mgr.ListComponents(); len(names) != 1 || names[0] != "core" {
+		t.Fatalf("Expected only the detected core component, got %v", names)
+	}
+	types, err := mgr.EffectiveTypes(&mgr.ProjectConfig.Components[0])
+	if err != nil || len(types) != 1 || types[0] != "rust" {
+		t.Errorf("Expected rust, got %v (%v)", types, err)
+	}
+}
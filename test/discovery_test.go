@@ -0,0 +1,128 @@
+package test
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComponentDiscovery(t *testing.T) {
+	projectDir := t.TempDir()
+	presetsDir := filepath.Join(projectDir, "presets")
+	os.MkdirAll(presetsDir, 0755)
+	os.WriteFile(filepath.Join(presetsDir, "pip.toml"), []byte(`
+[metadata]
+type = "pip"
+role = "package_manager"
+manifest_file = "requirements.txt"
+[scripts]
+run = "python main.py"
+`), 0644)
+
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "discovery"
+
+[discovery]
+paths = ["services/*"]
+
+[[components]]
+name = "gateway"
+type = "pip"
+path = "services/gateway"
+`), 0644)
+
+	for _, dir := range []string{"api", "worker", "gateway", "docs"} {
+		os.MkdirAll(filepath.Join(projectDir, "services", dir), 0755)
+	}
+	os.WriteFile(filepath.Join(projectDir, "services", "api", "component.toml"), []byte(`
+type = "pip"
+groups = ["backend"]
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "services", "worker", "component.yaml"), []byte(`
+name: jobs
+types: [pip]
+`), 0644)
+	// Declared components keep their directory, even with a fragment in it
+	os.WriteFile(filepath.Join(projectDir, "services", "gateway", "component.toml"), []byte(`type = "pip"`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	names := mgr.ListComponents()
+	if strings.Join(names, ",") != "gateway,api,jobs" {
+		t.Fatalf("Unexpected components: %v", names)
+	}
+	if backend := mgr.ListComponentsByGroup("backend"); len(backend) != 1 || backend[0] != "api" {
+		t.Errorf("Expected api in backend group, got %v", backend)
+	}
+
+	comp, err := mgr.ResolveComponent("jobs")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.AbsPath != filepath.Join(projectDir, "services", "worker") || comp.Scripts["run"].Cmd != "python main.py" {
+		t.Errorf("Unexpected resolved component: %+v", comp)
+	}
+
+	// Dependencies of a discovered component are written to its fragment only
+	if err := mgr.AddDependency("api", "flask"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	fragment, err := config.LoadComponentFragment(filepath.Join(projectDir, "services", "api", "component.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fragment.Dependencies) != 1 || fragment.Dependencies[0] != "flask" || fragment.Name != "" || fragment.Path != "" {
+		t.Errorf("Unexpected fragment after save: %+v", fragment)
+	}
+	if err := mgr.AddDependency("gateway", "requests"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	cfg, err := config.LoadProjectConfig(filepath.Join(projectDir, "mngproj.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Components) != 1 || cfg.Components[0].Dependencies[0] != "requests" {
+		t.Errorf("Discovered components must not be written to mngproj.toml: %+v", cfg.Components)
+	}
+}
+
+func TestDiscoverySkipsBrokenPresets(t *testing.T) {
+	projectDir := t.TempDir()
+	presetsDir := filepath.Join(projectDir, "presets")
+	os.MkdirAll(presetsDir, 0755)
+	os.WriteFile(filepath.Join(presetsDir, "broken.toml"), []byte("[metadata\ntype = "), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "pip.toml"), []byte(`
+[metadata]
+type = "pip"
+role = "package_manager"
+detect = { files = ["requirements.txt"] }
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "discovery"
+
+[discovery]
+paths = ["services/*"]
+`), 0644)
+	os.MkdirAll(filepath.Join(projectDir, "services", "api"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "services", "api", "requirements.txt"), nil, 0644)
+
+	var warnings strings.Builder
+	mgr, err := manager.NewWithOptions(projectDir, manager.Options{Warnings: &warnings})
+	if err != nil {
+		t.Fatalf("Manager New failed with a broken preset: %v", err)
+	}
+	if names := strings.Join(mgr.ListComponents(), ","); names != "api" {
+		t.Errorf("Unexpected components: %s", names)
+	}
+	if !strings.Contains(warnings.String(), `skipping preset "broken"`) {
+		t.Errorf("Expected a warning about the broken preset, got %q", warnings.String())
+	}
+}
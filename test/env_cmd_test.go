@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvCommands(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "app"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "app", "VERSION"), []byte("v1.2.3\n"), 0644)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "envcmd"
+
+[[components]]
+name = "app"
+type = "lang"
+path = "app"
+[components.env]
+VERSION = { cmd = "cat VERSION" }
+COUNT = { cmd = "echo x >> calls; wc -l < calls", cache = "run" }
+RUNS = { cmd = "echo x >> runs; wc -l < runs" }
+[components.scripts]
+show = "echo $VERSION $COUNT"
+prebuild = "echo pre $RUNS"
+build = ["echo first $RUNS", "echo second $RUNS"]
+[components.scripts.broken]
+cmd = "echo unreachable"
+env = { BAD = { cmd = "echo oops >&2; exit 3" } }
+[components.scripts.slow]
+cmd = "echo unreachable"
+env = { SLOW = { cmd = "exec sleep 5", timeout = "100ms" } }
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	for range 2 {
+		var stdout bytes.Buffer
+		if err := mgr.ExecuteScript("app", "show", nil, &stdout, nil); err != nil {
+			t.Fatalf("show failed: %v", err)
+		}
+		// The cached command only ran once
+		if got := strings.TrimSpace(stdout.String()); got != "v1.2.3 1" {
+			t.Errorf("unexpected output %q", got)
+		}
+	}
+
+	// Without a cache a command runs once per script run, shared by its steps and hooks.
+	// The two runs of show above already ran it twice.
+	for _, want := range []string{"pre 3\nfirst 3\nsecond 3\n", "pre 4\nfirst 4\nsecond 4\n"} {
+		var stdout bytes.Buffer
+		if err := mgr.ExecuteScript("app", "build", nil, &stdout, nil); err != nil {
+			t.Fatalf("build failed: %v", err)
+		}
+		if got := strings.ReplaceAll(stdout.String(), " ", ""); got != strings.ReplaceAll(want, " ", "") {
+			t.Errorf("unexpected output %q, want %q", stdout.String(), want)
+		}
+	}
+
+	err = mgr.ExecuteScript("app", "broken", nil, &bytes.Buffer{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "env BAD") || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected failing env command error, got %v", err)
+	}
+	err = mgr.ExecuteScript("app", "slow", nil, &bytes.Buffer{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("expected env command timeout, got %v", err)
+	}
+}
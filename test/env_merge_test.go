@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvMergeOperators(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "app"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+[env]
+PATH = { prepend = "/lang/bin" }
+LIBS = "/base"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "presets", "tool.toml"), []byte(`
+[metadata]
+type = "tool"
+role = "tool"
+[env]
+PATH = { prepend = "/tool/bin", append = "/tool/fallback" }
+LIBS = { append = "/tool/lib" }
+MODE = { default = "dev" }
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "envmerge"
+
+[[components]]
+name = "app"
+types = ["lang", "tool"]
+path = "app"
+[components.env]
+PATH = { prepend = "${MNGPROJ_COMPONENT_ROOT}/bin" }
+[components.scripts.show]
+cmd = "echo \"$PATH|$LIBS|$MODE\""
+env = { LIBS = { prepend = "/script/lib" } }
+`), 0644)
+
+	t.Setenv("PATH", "/usr/bin:/bin")
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	run := func() string {
+		var stdout bytes.Buffer
+		if err := mgr.ExecuteScript("app", "show", nil, &stdout, nil); err != nil {
+			t.Fatalf("show failed: %v", err)
+		}
+		return strings.TrimSpace(stdout.String())
+	}
+
+	appBin := filepath.Join(projectDir, "app", "bin")
+	want := appBin + ":/tool/bin:/lang/bin:/usr/bin:/bin:/tool/fallback|/script/lib:/base:/tool/lib|dev"
+	if got := run(); got != want {
+		t.Errorf("unexpected env\n got %q\nwant %q", got, want)
+	}
+
+	// A default does not replace a value from the process environment
+	t.Setenv("MODE", "prod")
+	if got := run(); !strings.HasSuffix(got, "|prod") {
+		t.Errorf("expected MODE from the process environment, got %q", got)
+	}
+}
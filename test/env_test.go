@@ -0,0 +1,100 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/cmd"
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Setenv("DOTENV_TEST_HOME", "/home/test")
+	content := `# comment
+export PLAIN=value # trailing comment
+SINGLE='literal ${PLAIN} # not a comment'
+DOUBLE="line1\nline2 ${PLAIN}"
+MULTI="first
+second"
+EXPANDED=${DOTENV_TEST_HOME}/bin
+ESCAPED="cost \$PLAIN \${PLAIN} ${PLAIN}"
+
+EMPTY=
+`
+	env, err := config.ParseDotenv(content)
+	if err != nil {
+		t.Fatalf("ParseDotenv failed: %v", err)
+	}
+	expected := map[string]string{
+		"PLAIN":    "value",
+		"SINGLE":   "literal ${PLAIN} # not a comment",
+		"DOUBLE":   "line1\nline2 value",
+		"MULTI":    "first\nsecond",
+		"EXPANDED": "/home/test/bin",
+		"ESCAPED":  "cost $PLAIN ${PLAIN} value",
+		"EMPTY":    "",
+	}
+	for k, v := range expected {
+		if env[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, env[k])
+		}
+	}
+
+	if _, err := config.ParseDotenv("BROKEN=\"unterminated\n"); err == nil {
+		t.Error("Expected error for unterminated quote")
+	}
+}
+
+func TestEnvFilesAndOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "api"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("SHARED=project\nLEVEL=project\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "api", ".env"), []byte("SHARED=component\nLEVEL=component\nCLI=file\n"), 0644)
+
+	cfg := &config.ProjectConfig{
+		Project: config.ProjectMeta{EnvFiles: []string{".env"}},
+		Components: []config.ComponentConfig{
+			{
+				Name:     "api",
+				Path:     "api",
+				Env:      map[string]config.EnvValue{"LEVEL": {Value: "inline"}, "INLINE": {Value: "yes"}},
+				EnvFiles: []string{".env", ".env.local"}, // .env.local does not exist
+				Scripts:  map[string]config.Script{"show": {Cmd: "echo $SHARED $LEVEL $INLINE $CLI"}, "echoargs": {Cmd: "echo got"}},
+			},
+		},
+	}
+	mgr := &manager.Manager{ProjectConfig: cfg, ProjectDir: tmpDir, PresetsDir: tmpDir}
+
+	rest, err := cmd.ParseEnvFlags(mgr, []string{"api", "--env", "CLI=flag", "--", "--env", "X=1"})
+	if err != nil {
+		t.Fatalf("ParseEnvFlags failed: %v", err)
+	}
+	// The separator itself is dropped
+	if strings.Join(rest, " ") != "api --env X=1" {
+		t.Errorf("Unexpected remaining args: %v", rest)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("api", "show", nil, &stdout, nil); err != nil {
+		t.Fatalf("ExecuteScript failed: %v", err)
+	}
+	// The inline env wins over the env files, which apply in order
+	if strings.TrimSpace(stdout.String()) != "component inline yes flag" {
+		t.Errorf("Unexpected env precedence, got %q", stdout.String())
+	}
+
+	rest, err = cmd.ParseEnvFlags(mgr, []string{"api", "--", "--flag"})
+	if err != nil {
+		t.Fatalf("ParseEnvFlags failed: %v", err)
+	}
+	stdout.Reset()
+	if err := mgr.ExecuteScript(rest[0], "echoargs", rest[1:], &stdout, nil); err != nil {
+		t.Fatalf("ExecuteScript failed: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "got --flag" {
+		t.Errorf("Expected arguments after -- without the separator, got %q", got)
+	}
+}
@@ -25,12 +25,12 @@ role="tool"
 				Name: "app",
 				Type: "custom",
 				Path: ".",
-				Scripts: map[string]string{
-					"echo_args": "echo {{range .Args}}{{.}} {{end}}",
-					"echo_env":  "echo {{.Env.MY_VAR}}",
+				Scripts: map[string]config.Script{
+					"echo_args": {Cmd: "echo {{range .Args}}{{.}} {{end}}"},
+					"echo_env":  {Cmd: "echo {{.Env.MY_VAR}}"},
 				},
-				Env: map[string]string{
-					"MY_VAR": "hello_world",
+				Env: map[string]config.EnvValue{
+					"MY_VAR": {Value: "hello_world"},
 				},
 			},
 		},
@@ -73,8 +73,8 @@ func TestExecuteScriptExternalFile(t *testing.T) {
 			{
 				Name: "app",
 				Path: ".",
-				Scripts: map[string]string{
-					"run_file": "file:script.sh",
+				Scripts: map[string]config.Script{
+					"run_file": {Cmd: "file:script.sh"},
 				},
 			},
 		},
@@ -95,3 +95,53 @@ func TestExecuteScriptExternalFile(t *testing.T) {
 		t.Errorf("Expected 'from file', got '%s'", stdout.String())
 	}
 }
+
+func TestPresetParams(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "img.toml"), []byte(`
+[metadata]
+type = "img"
+role = "tool"
+[scripts]
+build = "echo build {{.Params.image}} entry={{.Params.entrypoint}}"
+[env]
+IMAGE_TAG = "{{.Params.image}}:latest"
+[params]
+image = "{{.Name}}"
+entrypoint = "main.py"
+workers = 2
+`), 0644)
+
+	cfg := &config.ProjectConfig{
+		Components: []config.ComponentConfig{
+			{Name: "api", Type: "img", Path: ".", Params: map[string]any{"entrypoint": "src/app.py"},
+				Scripts: map[string]config.Script{"tag": {Cmd: "echo $IMAGE_TAG"}}},
+			{Name: "typo", Type: "img", Path: ".", Params: map[string]any{"entrypiont": "x.py"}},
+			{Name: "badtype", Type: "img", Path: ".", Params: map[string]any{"workers": "many"}},
+		},
+	}
+	mgr := &manager.Manager{ProjectConfig: cfg, ProjectDir: tmpDir, PresetsDir: tmpDir}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("api", "build", []string{"--push"}, &stdout, nil); err != nil {
+		t.Fatalf("ExecuteScript failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "build api entry=src/app.py --push") {
+		t.Errorf("Expected rendered params and appended args, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := mgr.ExecuteScript("api", "tag", nil, &stdout, nil); err != nil {
+		t.Fatalf("ExecuteScript failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "api:latest") {
+		t.Errorf("Expected params in env, got %q", stdout.String())
+	}
+
+	if _, err := mgr.ResolveComponent("typo"); err == nil || !strings.Contains(err.Error(), "unknown param") {
+		t.Errorf("Expected unknown param error, got %v", err)
+	}
+	if _, err := mgr.ResolveComponent("badtype"); err == nil {
+		t.Error("Expected type mismatch error for param override")
+	}
+}
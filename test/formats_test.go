@@ -0,0 +1,86 @@
+package test
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLAndJSONProjectConfigs(t *testing.T) {
+	t.Setenv("MNGPROJ_PRESETS_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	yamlDir := t.TempDir()
+	os.WriteFile(filepath.Join(yamlDir, "mngproj.yaml"), []byte(`
+project:
+  name: yaml-project
+components:
+  - name: app
+    types: [local]
+    scripts:
+      hello: echo yaml
+`), 0644)
+	os.MkdirAll(filepath.Join(yamlDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(yamlDir, "presets", "local.yml"), []byte(`
+metadata:
+  type: local
+  role: tool
+scripts:
+  build: make
+params:
+  jobs: 4
+`), 0644)
+
+	mgr, err := manager.New(yamlDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if mgr.ProjectConfig.Project.Name != "yaml-project" {
+		t.Errorf("Expected yaml-project, got %q", mgr.ProjectConfig.Project.Name)
+	}
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Scripts["build"].Cmd != "make" || comp.Scripts["hello"].Cmd != "echo yaml" {
+		t.Errorf("Unexpected scripts: %v", comp.Scripts)
+	}
+
+	// Saving keeps the source format
+	if err := mgr.AddDependency("app", "left-pad"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(yamlDir, "mngproj.yaml"))
+	if !strings.Contains(string(data), "- left-pad") {
+		t.Errorf("Expected YAML output with the new dependency, got:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(yamlDir, "mngproj.toml")); err == nil {
+		t.Error("Saving a YAML project must not create mngproj.toml")
+	}
+
+	jsonDir := t.TempDir()
+	os.WriteFile(filepath.Join(jsonDir, "mngproj.json"), []byte(`{
+  "project": {"name": "json-project"},
+  "components": [{"name": "api", "type": "go", "path": "api"}]
+}`), 0644)
+	cfg, err := config.LoadProjectConfig(filepath.Join(jsonDir, "mngproj.json"))
+	if err != nil {
+		t.Fatalf("LoadProjectConfig failed: %v", err)
+	}
+	if cfg.Project.Name != "json-project" || cfg.Components[0].Type != "go" || cfg.Components[0].Path != "api" {
+		t.Errorf("Unexpected JSON config: %+v", cfg)
+	}
+}
+
+func TestMultipleConfigFormatsIsError(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mngproj.toml"), []byte("[project]\nname = \"a\"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "mngproj.json"), []byte(`{"project": {"name": "b"}}`), 0644)
+
+	if _, err := manager.FindConfigFile(dir); err == nil || !strings.Contains(err.Error(), "multiple config files") {
+		t.Errorf("Expected multiple config files error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlobalConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		t.Fatalf("LoadGlobalConfig without a file failed: %v", err)
+	}
+	if cfg.Jobs != 0 || cfg.Color != "" {
+		t.Errorf("expected an empty config, got %+v", cfg)
+	}
+
+	presetDir := t.TempDir()
+	for key, value := range map[string]string{"jobs": "4", "color": "never", "log_format": "json", "preset_dirs": presetDir, "init_type": "python"} {
+		if err := cfg.Set(key, value); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+	for key, value := range map[string]string{"jobs": "0", "color": "rainbow", "log_format": "xml", "nope": "1"} {
+		if err := cfg.Set(key, value); err == nil {
+			t.Errorf("Set %s=%s should fail", key, value)
+		}
+	}
+	if err := config.SaveGlobalConfig(cfg); err != nil {
+		t.Fatalf("SaveGlobalConfig failed: %v", err)
+	}
+
+	cfg, err = config.LoadGlobalConfig()
+	if err != nil {
+		t.Fatalf("LoadGlobalConfig failed: %v", err)
+	}
+	if jobs, _ := cfg.Get("jobs"); jobs != "4" {
+		t.Errorf("jobs = %q, want 4", jobs)
+	}
+	if !slices.Equal(cfg.PresetDirs, []string{presetDir}) || cfg.InitType != "python" {
+		t.Errorf("unexpected config after reload: %+v", cfg)
+	}
+
+	// preset_dirs extend the search path and log_format applies to aggregated logs
+	os.WriteFile(filepath.Join(presetDir, "mine.toml"), []byte(`
+[metadata]
+type = "mine"
+role = "language"
+[scripts]
+run = "echo mine"
+`), 0644)
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "global"
+
+[[components]]
+name = "app"
+type = "mine"
+`), 0644)
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	pw := mgr.LogWriter("app")
+	var out bytes.Buffer
+	pw.Writer = &out
+	if err := mgr.ExecuteScript("app", "run", nil, pw, pw); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"component":"app","line":"mine"}` {
+		t.Errorf("unexpected log output %q", got)
+	}
+}
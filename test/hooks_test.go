@@ -0,0 +1,103 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHooksAndSteps(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+[scripts]
+build = "echo lang-build"
+prebuild = "echo lang-prebuild"
+postbuild = "echo lang-postbuild"
+prefix = "echo not-a-hook"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "presets", "fw.toml"), []byte(`
+[metadata]
+type = "fw"
+role = "framework"
+[scripts]
+build = ["echo fw-step1", "echo fw-step2"]
+prebuild = "echo fw-prebuild"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "hooks"
+
+[[components]]
+name = "app"
+types = ["lang", "fw"]
+[components.scripts]
+broken = ["echo first", "false", "echo never"]
+pretest = "echo component-pretest"
+test = "echo test"
+
+[[components]]
+name = "custom"
+types = ["lang", "fw"]
+[components.scripts]
+postbuild = "echo custom-postbuild"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("app", "build", []string{"--release"}, &stdout, nil); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	want := "lang-prebuild\nfw-prebuild\nfw-step1\nfw-step2 --release\nlang-postbuild\n"
+	if stdout.String() != want {
+		t.Errorf("Unexpected build output:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	err = mgr.ExecuteScript("app", "broken", nil, &stdout, nil)
+	if err == nil || !strings.Contains(err.Error(), "step 2/3") {
+		t.Errorf("Expected failure at step 2, got %v", err)
+	}
+	if stdout.String() != "first\n" {
+		t.Errorf("Steps must stop at the first failure, got %q", stdout.String())
+	}
+
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := comp.Hooks["prefix"]; ok {
+		t.Error("prefix is not a hook when no 'fix' script exists")
+	}
+	if len(comp.Hooks["pretest"]) != 1 {
+		t.Errorf("Expected component pretest hook, got %v", comp.Hooks)
+	}
+
+	// A component hook replaces the preset hooks, and async runs pre hooks before starting
+	stdout.Reset()
+	cmd, err := mgr.ExecuteScriptAsync("custom", "build", nil, &stdout, nil)
+	if err != nil {
+		t.Fatalf("async build failed: %v", err)
+	}
+	cmd.Wait()
+	if stdout.String() != "lang-prebuild\nfw-prebuild\nfw-step1\nfw-step2\n" {
+		t.Errorf("Unexpected async output: %q", stdout.String())
+	}
+	stdout.Reset()
+	if err := mgr.ExecuteScript("custom", "build", nil, &stdout, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(stdout.String(), "fw-step2\ncustom-postbuild\n") {
+		t.Errorf("Expected component postbuild hook only, got %q", stdout.String())
+	}
+}
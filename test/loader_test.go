@@ -4,6 +4,7 @@ import (
 	"mngproj/pkg/config"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +30,60 @@ type = "go"
 		t.Error("Expected error for duplicate component name, got nil")
 	}
 }
+
+func TestPresetExtends(t *testing.T) {
+	presetsDir := t.TempDir()
+	os.WriteFile(filepath.Join(presetsDir, "base.toml"), []byte(`
+gitignore = ["__pycache__/"]
+[metadata]
+type = "base"
+role = "language"
+required_tools = ["python"]
+[scripts]
+run = "python main.py"
+test = "python -m unittest"
+[env]
+PYTHONUNBUFFERED = "1"
+`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "child.toml"), []byte(`
+extends = ["base"]
+gitignore = [".libs/"]
+[metadata]
+type = "child"
+role = "package_manager"
+required_tools = ["pip"]
+[scripts]
+test = "pytest"
+`), 0644)
+
+	preset, err := config.LoadPreset(presetsDir, "child")
+	if err != nil {
+		t.Fatalf("LoadPreset(child) failed: %v", err)
+	}
+	if preset.Scripts["run"].Cmd != "python main.py" {
+		t.Errorf("Expected inherited run script, got %q", preset.Scripts["run"].Cmd)
+	}
+	if preset.Scripts["test"].Cmd != "pytest" {
+		t.Errorf("Expected overridden test script, got %q", preset.Scripts["test"].Cmd)
+	}
+	if preset.Env["PYTHONUNBUFFERED"].Value != "1" {
+		t.Errorf("Expected inherited env, got %v", preset.Env)
+	}
+	if preset.Metadata.Role != "package_manager" || preset.Metadata.Type != "child" {
+		t.Errorf("Expected child metadata to win, got %+v", preset.Metadata)
+	}
+	if len(preset.Metadata.Gitignore) != 2 || len(preset.Metadata.RequiredTools) != 2 {
+		t.Errorf("Expected accumulated gitignore and tools, got %v / %v", preset.Metadata.Gitignore, preset.Metadata.RequiredTools)
+	}
+}
+
+func TestPresetExtendsCycle(t *testing.T) {
+	presetsDir := t.TempDir()
+	os.WriteFile(filepath.Join(presetsDir, "a.toml"), []byte(`extends = ["b"]`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "b.toml"), []byte(`extends = ["a"]`), 0644)
+
+	_, err := config.LoadPreset(presetsDir, "a")
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected inheritance cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLocalConfigOverride(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "override"
+
+[[components]]
+name = "api"
+type = "lang"
+dependencies = ["shared-dep"]
+ports = { http = 8000, admin = 8001 }
+[components.env]
+MODE = "shared"
+KEEP = "yes"
+
+[[components]]
+name = "worker"
+type = "lang"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.local.yaml"), []byte(`
+components:
+  - name: api
+    ports:
+      http: 9000
+    env:
+      MODE: local
+  - name: worker
+    disabled: true
+  - name: tools
+    type: lang
+    dependencies: [local-dep]
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if got := mgr.ListComponents(); !slices.Equal(got, []string{"api", "tools"}) {
+		t.Fatalf("components = %v, want [api tools]", got)
+	}
+	api := mgr.ProjectConfig.Components[0]
+	if api.Env["MODE"].Value != "local" || api.Env["KEEP"].Value != "yes" {
+		t.Errorf("env not merged: %v", api.Env)
+	}
+	ports, err := mgr.Ports()
+	if err != nil {
+		t.Fatalf("Ports failed: %v", err)
+	}
+	if ports["api"]["http"] != 9000 || ports["api"]["admin"] != 8001 {
+		t.Errorf("ports not merged: %v", ports["api"])
+	}
+
+	// The shared file owns api's dependencies and gets none of the local values
+	if err := mgr.AddDependency("api", "new-dep"); err != nil {
+		t.Fatalf("AddDependency api failed: %v", err)
+	}
+	shared, err := os.ReadFile(filepath.Join(projectDir, "mngproj.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(shared), "new-dep") {
+		t.Errorf("api dependency not saved to the shared config:\n%s", shared)
+	}
+	for _, leaked := range []string{"local", "9000", "tools", "disabled = true"} {
+		if strings.Contains(string(shared), leaked) {
+			t.Errorf("shared config contains local value %q:\n%s", leaked, shared)
+		}
+	}
+
+	// tools only exists in the local file
+	if err := mgr.AddDependency("tools", "tool-dep"); err != nil {
+		t.Fatalf("AddDependency tools failed: %v", err)
+	}
+	local, err := config.LoadLocalConfig(filepath.Join(projectDir, "mngproj.local.yaml"))
+	if err != nil {
+		t.Fatalf("LoadLocalConfig failed: %v", err)
+	}
+	tools := local.Components[2]
+	if !slices.Equal(tools.Dependencies, []string{"local-dep", "tool-dep"}) || tools.Path != "" {
+		t.Errorf("unexpected local tools component: %+v", tools)
+	}
+}
+
+func TestLocalConfigSurvivesAdd(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "survive"
+env_files = [".env"]
+
+[discovery]
+paths = ["libs/*"]
+
+[[components]]
+name = "api"
+types = ["lang"]
+groups = ["backend"]
+env_files = [".env.api"]
+[components.scripts]
+serve = "echo serve"
+`), 0644)
+	localPath := filepath.Join(projectDir, "mngproj.local.toml")
+	os.WriteFile(localPath, []byte(`# my overrides
+[[components]]
+name = "api"
+groups = []
+dependencies = ["local-dep"] # pinned
+[components.env]
+MODE = "local"
+`), 0644)
+
+	var warnings strings.Builder
+	mgr, err := manager.NewWithOptions(projectDir, manager.Options{Warnings: &warnings})
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if err := mgr.AddDependency("api", "new-dep"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	// Re-reading the config while saving does not repeat the outdated-schema warning
+	if got := strings.Count(warnings.String(), "uses config schema"); got != 1 {
+		t.Errorf("expected one schema warning, got %q", warnings.String())
+	}
+
+	// Only the dependencies line changes
+	want := `# my overrides
+[[components]]
+name = "api"
+groups = []
+dependencies = ["local-dep", "new-dep"] # pinned
+[components.env]
+MODE = "local"
+`
+	if data, _ := os.ReadFile(localPath); string(data) != want {
+		t.Errorf("unexpected local config:\n%s\nwant:\n%s", data, want)
+	}
+
+	mgr, err = manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New after add failed: %v", err)
+	}
+	cfg := mgr.ProjectConfig
+	api := cfg.Components[0]
+	if !slices.Equal(cfg.Project.EnvFiles, []string{".env"}) || !slices.Equal(cfg.Discovery.Paths, []string{"libs/*"}) {
+		t.Errorf("project settings lost: %+v %+v", cfg.Project, cfg.Discovery)
+	}
+	if !slices.Equal(api.Types, []string{"lang"}) || !slices.Equal(api.Groups, []string{"backend"}) || !slices.Equal(api.EnvFiles, []string{".env.api"}) {
+		t.Errorf("component settings lost: %+v", api)
+	}
+	if api.Scripts["serve"].Cmd != "echo serve" || api.Env["MODE"].Value != "local" {
+		t.Errorf("scripts or env lost: %+v", api)
+	}
+	if !slices.Equal(api.Dependencies, []string{"local-dep", "new-dep"}) {
+		t.Errorf("dependencies = %v", api.Dependencies)
+	}
+}
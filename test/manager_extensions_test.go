@@ -45,8 +45,8 @@ run = "mytool run"
 		PresetsDir:    presetsDir,
 	}
 	compDefault, _ := mgrDefault.ResolveComponent("app")
-	if compDefault.Scripts["run"] != "django run" {
-		t.Errorf("Default: Expected 'django run', got '%s'", compDefault.Scripts["run"])
+	if compDefault.Scripts["run"].Cmd != "django run" {
+		t.Errorf("Default: Expected 'django run', got '%s'", compDefault.Scripts["run"].Cmd)
 	}
 
 	// Scenario B: User Override (Tool > Framework)
@@ -69,8 +69,8 @@ run = "mytool run"
 		PresetsDir:    presetsDir,
 	}
 	compOverride, _ := mgrOverride.ResolveComponent("app")
-	if compOverride.Scripts["run"] != "mytool run" {
-		t.Errorf("Override: Expected 'mytool run', got '%s'", compOverride.Scripts["run"])
+	if compOverride.Scripts["run"].Cmd != "mytool run" {
+		t.Errorf("Override: Expected 'mytool run', got '%s'", compOverride.Scripts["run"].Cmd)
 	}
 }
 
@@ -91,12 +91,12 @@ func TestInstallScriptDefinition(t *testing.T) {
 	}
 
 	expectedInstall := "pip install --target=.libs"
-	if pipCfg.Scripts["install_pkg"] != expectedInstall {
-		t.Errorf("Expected install_pkg='%s', got '%s'", expectedInstall, pipCfg.Scripts["install_pkg"])
+	if pipCfg.Scripts["install_pkg"].Cmd != expectedInstall {
+		t.Errorf("Expected install_pkg='%s', got '%s'", expectedInstall, pipCfg.Scripts["install_pkg"].Cmd)
 	}
 
 	// Check env
-	if pipCfg.Env["PYTHONPATH"] == "" {
+	if pipCfg.Env["PYTHONPATH"].Value == "" {
 		t.Error("PYTHONPATH should be set for isolation")
 	}
 }
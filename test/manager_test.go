@@ -5,6 +5,7 @@ import (
 	"mngproj/pkg/manager"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -40,8 +41,8 @@ build = "cargo build"
 		t.Fatalf("ResolveComponent(backend) failed: %v", err)
 	}
 
-	if comp.Scripts["run"] != "cargo run" {
-		t.Errorf("Expected run script 'cargo run', got '%s'", comp.Scripts["run"])
+	if comp.Scripts["run"].Cmd != "cargo run" {
+		t.Errorf("Expected run script 'cargo run', got '%s'", comp.Scripts["run"].Cmd)
 	}
 }
 
@@ -109,18 +110,18 @@ run = "python manage.py runserver"
 	}
 
 	// Verify RUN (Framework wins)
-	if comp.Scripts["run"] != "python manage.py runserver" {
-		t.Errorf("RUN: Expected 'python manage.py runserver', got '%s'", comp.Scripts["run"])
+	if comp.Scripts["run"].Cmd != "python manage.py runserver" {
+		t.Errorf("RUN: Expected 'python manage.py runserver', got '%s'", comp.Scripts["run"].Cmd)
 	}
 
 	// Verify INSTALL (Package Manager wins)
-	if comp.Scripts["install"] != "uv sync" {
-		t.Errorf("INSTALL: Expected 'uv sync', got '%s'", comp.Scripts["install"])
+	if comp.Scripts["install"].Cmd != "uv sync" {
+		t.Errorf("INSTALL: Expected 'uv sync', got '%s'", comp.Scripts["install"].Cmd)
 	}
 
 	// Verify TEST (Language wins as fallback)
-	if comp.Scripts["test"] != "unittest" {
-		t.Errorf("TEST: Expected 'unittest', got '%s'", comp.Scripts["test"])
+	if comp.Scripts["test"].Cmd != "unittest" {
+		t.Errorf("TEST: Expected 'unittest', got '%s'", comp.Scripts["test"].Cmd)
 	}
 }
 
@@ -158,3 +159,58 @@ func TestAllPresetsLoad(t *testing.T) {
 		t.Fatalf("Walk failed: %v", err)
 	}
 }
+
+func TestResolveComponentRequiresAndConflicts(t *testing.T) {
+	presetsDir := t.TempDir()
+	os.WriteFile(filepath.Join(presetsDir, "node.toml"), []byte(`
+[metadata]
+type = "node"
+role = "language"
+[scripts]
+run = "npm start"
+install = "npm install"
+`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "react.toml"), []byte(`
+[metadata]
+type = "react"
+role = "framework"
+requires = ["node"]
+conflicts = ["vuejs"]
+[scripts]
+run = "npm run dev"
+`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "vuejs.toml"), []byte(`
+[metadata]
+type = "vuejs"
+role = "framework"
+`), 0644)
+
+	mgr := &manager.Manager{
+		ProjectConfig: &config.ProjectConfig{
+			Components: []config.ComponentConfig{
+				{Name: "web", Types: []string{"react"}, Path: "."},
+				{Name: "mixed", Types: []string{"react", "vuejs"}, Path: "."},
+			},
+		},
+		ProjectDir: "/tmp/requires",
+		PresetsDir: presetsDir,
+	}
+
+	comp, err := mgr.ResolveComponent("web")
+	if err != nil {
+		t.Fatalf("ResolveComponent(web) failed: %v", err)
+	}
+	if strings.Join(comp.Types, ",") != "node,react" {
+		t.Errorf("Expected effective types node,react, got %v", comp.Types)
+	}
+	if comp.Scripts["install"].Cmd != "npm install" || comp.Scripts["run"].Cmd != "npm run dev" {
+		t.Errorf("Expected scripts from required node preset, got %v", comp.Scripts)
+	}
+
+	if _, err := mgr.ResolveComponent("mixed"); err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+	if err := mgr.ValidateTools(); err == nil {
+		t.Error("Expected ValidateTools to report the conflict")
+	}
+}
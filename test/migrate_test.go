@@ -0,0 +1,221 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "services", "worker"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+gitignore = ["dist/"]
+
+[metadata]
+type = "lang"
+role = "language"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "services", "worker", "component.yaml"), []byte("type: lang\n"), 0644)
+	configPath := filepath.Join(projectDir, "mngproj.toml")
+	os.WriteFile(configPath, []byte(`
+[project]
+name = "old"
+
+[discovery]
+paths = ["services/*"]
+
+[[components]]
+name = "app"
+type = "lang"
+
+[[components]]
+name = "both"
+type = "ignored"
+types = ["lang"]
+`), 0644)
+
+	var warnings bytes.Buffer
+	mgr, err := manager.NewWithOptions(projectDir, manager.Options{Warnings: &warnings})
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if got := strings.Count(warnings.String(), "uses config schema 1"); got != 1 {
+		t.Errorf("expected one outdated schema warning, got %q", warnings.String())
+	}
+
+	// A check leaves the files alone
+	changes, err := mgr.Migrate(false)
+	if err != nil {
+		t.Fatalf("Migrate check failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 files to migrate, got %+v", changes)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), `type = "lang"`) {
+		t.Fatal("check mode rewrote the config")
+	}
+
+	if _, err := mgr.Migrate(true); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	cfg, err := config.LoadProjectConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig after migrate failed: %v", err)
+	}
+	if cfg.Project.Schema != config.CurrentSchema || cfg.Project.Name != "old" {
+		t.Errorf("unexpected project after migrate: %+v", cfg.Project)
+	}
+	for _, c := range cfg.Components {
+		if c.Type != "" || !slices.Equal(c.Types, []string{"lang"}) {
+			t.Errorf("component %s: type %q, types %v", c.Name, c.Type, c.Types)
+		}
+	}
+	fragment, err := config.LoadComponentFragment(filepath.Join(projectDir, "services", "worker", "component.yaml"))
+	if err != nil || fragment.Type != "" || !slices.Equal(fragment.Types, []string{"lang"}) {
+		t.Errorf("fragment not migrated: %+v, %v", fragment, err)
+	}
+	preset, err := config.DecodeFile(filepath.Join(projectDir, "presets", "lang.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := preset["gitignore"]; ok || preset["metadata"].(map[string]any)["gitignore"] == nil {
+		t.Errorf("preset gitignore not moved: %v", preset)
+	}
+
+	mgr, err = manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New after migrate failed: %v", err)
+	}
+	if changes, err := mgr.Migrate(false); err != nil || len(changes) != 0 {
+		t.Errorf("expected nothing left to migrate, got %+v, %v", changes, err)
+	}
+	resolved, err := mgr.ResolveComponent("worker")
+	if err != nil || !slices.Equal(resolved.Types, []string{"lang"}) {
+		t.Errorf("worker after migrate: %+v, %v", resolved, err)
+	}
+
+	os.WriteFile(configPath, []byte("[project]\nname = \"future\"\nschema = 99\n"), 0644)
+	if _, err := config.LoadProjectConfig(configPath); err == nil || !strings.Contains(err.Error(), "only supports up to") {
+		t.Errorf("expected an error for a newer schema, got %v", err)
+	}
+}
+
+func TestInitWritesCurrentSchema(t *testing.T) {
+	projectDir := t.TempDir()
+	t.Chdir(projectDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := manager.InitializeProject("python"); err != nil {
+		t.Fatalf("InitializeProject failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(projectDir, "mngproj.toml"))
+	if !strings.Contains(string(data), `types = ["python"]`) || strings.Contains(string(data), "\ntype =") {
+		t.Errorf("init should declare types, not the legacy type:\n%s", data)
+	}
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if changes, err := mgr.Migrate(false); err != nil || len(changes) != 0 {
+		t.Errorf("new config should need no migration, got %v (%v)", changes, err)
+	}
+}
+
+func TestMigrateKeepsFormatting(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	presetPath := filepath.Join(projectDir, "presets", "lang.toml")
+	os.WriteFile(presetPath, []byte(`# Preset for lang
+gitignore = [
+  "dist/", # build output
+]
+
+[metadata]
+type = "lang" # the type
+role = "language"
+`), 0644)
+	configPath := filepath.Join(projectDir, "mngproj.toml")
+	os.WriteFile(configPath, []byte(`# Project config
+[project]
+name = "old" # keep me
+
+# --- Components ---
+[[components]]
+name = "app"
+type = "lang" # legacy
+path = "."
+[components.scripts]
+migrate = { cmd = "echo 'migrating'", description = "Run migrations" }
+
+[[components]]
+name = "empty"
+type = "lang"
+types = []
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if _, err := mgr.Migrate(true); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	want := `# Project config
+[project]
+name = "old" # keep me
+schema = 3
+
+# --- Components ---
+[[components]]
+name = "app"
+types = ["lang"] # legacy
+path = "."
+[components.scripts]
+migrate = { cmd = "echo 'migrating'", description = "Run migrations" }
+
+[[components]]
+name = "empty"
+types = ["lang"]
+`
+	if string(data) != want {
+		t.Errorf("unexpected config after migrate:\n%s\nwant:\n%s", data, want)
+	}
+
+	data, _ = os.ReadFile(presetPath)
+	want = `# Preset for lang
+
+[metadata]
+type = "lang" # the type
+role = "language"
+gitignore = [
+  "dist/", # build output
+]
+`
+	if string(data) != want {
+		t.Errorf("unexpected preset after migrate:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Shapes that cannot be edited in place are reported instead of rewritten
+	inline := "components = [{ name = \"app\", type = \"lang\" }]\n\n[project]\nname = \"inline\"\n"
+	os.WriteFile(configPath, []byte(inline), 0644)
+	os.Remove(presetPath)
+	if mgr, err = manager.New(projectDir); err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	_, err = mgr.Migrate(true)
+	if err == nil || !strings.Contains(err.Error(), "cannot be migrated in place") {
+		t.Errorf("expected an in-place migration error, got %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != inline {
+		t.Errorf("config was modified: %s", data)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func writePortsProject(t *testing.T, apiPorts string) string {
+	t.Helper()
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+[env]
+PORT = "${PORT_HTTP}"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "ports"
+
+[[components]]
+name = "api"
+type = "lang"
+ports = `+apiPorts+`
+[components.scripts]
+show = "echo $PORT_HTTP $PORT"
+
+[[components]]
+name = "web"
+type = "lang"
+ports = { http = "auto" }
+[components.scripts]
+show = "echo $PORT_HTTP $MNGPROJ_SVC_API_HTTP_URL"
+`), 0644)
+	return projectDir
+}
+
+func TestPortAllocation(t *testing.T) {
+	mgr, err := manager.New(writePortsProject(t, `{ http = "auto" }`))
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if err := mgr.AllocatePorts(mgr.ListComponents()); err != nil {
+		t.Fatalf("AllocatePorts failed: %v", err)
+	}
+	ports, err := mgr.Ports()
+	if err != nil {
+		t.Fatalf("Ports failed: %v", err)
+	}
+	api, web := ports["api"]["http"], ports["web"]["http"]
+	if api == 0 || web == 0 || api == web {
+		t.Fatalf("unexpected ports: %v", ports)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("api", "show", nil, &stdout, nil); err != nil {
+		t.Fatalf("api show failed: %v", err)
+	}
+	if got, want := strings.TrimSpace(stdout.String()), strconv.Itoa(api)+" "+strconv.Itoa(api); got != want {
+		t.Errorf("api env = %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if err := mgr.ExecuteScript("web", "show", nil, &stdout, nil); err != nil {
+		t.Fatalf("web show failed: %v", err)
+	}
+	want := strconv.Itoa(web) + " http://localhost:" + strconv.Itoa(api)
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("web env = %q, want %q", got, want)
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer l.Close()
+	busy := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	mgr, err := manager.New(writePortsProject(t, `{ http = `+busy+`, admin = `+busy+` }`))
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	err = mgr.AllocatePorts(mgr.ListComponents())
+	if err == nil {
+		t.Fatal("expected port conflicts")
+	}
+	for _, want := range []string{"declared by both api.admin and api.http", "(api.admin) is already in use"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
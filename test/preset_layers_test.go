@@ -0,0 +1,115 @@
+package test
+
+import (
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbeddedPresetsFallback(t *testing.T) {
+	t.Setenv("MNGPROJ_PRESETS_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	preset, err := config.LoadPresetFromLayers(manager.PresetSearchPath(""), "go")
+	if err != nil {
+		t.Fatalf("Failed to load embedded go preset: %v", err)
+	}
+	if preset.Scripts["test"].Cmd != "go test ./..." {
+		t.Errorf("Unexpected embedded go preset scripts: %v", preset.Scripts)
+	}
+}
+
+func TestProjectPresetsDirWins(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	projectDir := t.TempDir()
+	envDir := t.TempDir()
+	t.Setenv("MNGPROJ_PRESETS_DIR", envDir)
+
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "layers"
+[[components]]
+name = "app"
+types = ["go", "extra"]
+`), 0644)
+	os.MkdirAll(filepath.Join(projectDir, "presets", "languages"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "languages", "go.toml"), []byte(`
+[metadata]
+type = "go"
+role = "language"
+[scripts]
+run = "go run ./cmd/app"
+`), 0644)
+	os.WriteFile(filepath.Join(envDir, "extra.toml"), []byte(`
+[metadata]
+type = "extra"
+role = "tool"
+[scripts]
+lint = "extra lint"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Scripts["run"].Cmd != "go run ./cmd/app" {
+		t.Errorf("Expected project preset to override embedded go, got %q", comp.Scripts["run"].Cmd)
+	}
+	if comp.Scripts["lint"].Cmd != "extra lint" {
+		t.Errorf("Expected preset from MNGPROJ_PRESETS_DIR, got %q", comp.Scripts["lint"].Cmd)
+	}
+}
+
+func TestEjectPresets(t *testing.T) {
+	destDir := t.TempDir()
+	written, err := manager.EjectPresets(destDir, []string{"python"}, false)
+	if err != nil {
+		t.Fatalf("EjectPresets failed: %v", err)
+	}
+	if len(written) != 1 {
+		t.Fatalf("Expected a single ejected file, got %v", written)
+	}
+	if _, err := config.LoadPreset(destDir, "python"); err != nil {
+		t.Errorf("Ejected preset does not load: %v", err)
+	}
+	if _, err := manager.EjectPresets(destDir, []string{"does-not-exist"}, false); err == nil {
+		t.Error("Expected error for unknown preset name")
+	}
+}
+
+func TestPresetRegistryAmbiguityAndCache(t *testing.T) {
+	presetsDir := t.TempDir()
+	os.MkdirAll(filepath.Join(presetsDir, "languages"), 0755)
+	os.MkdirAll(filepath.Join(presetsDir, "tools"), 0755)
+	os.WriteFile(filepath.Join(presetsDir, "languages", "dup.toml"), []byte(`[metadata]
+type = "dup"`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "tools", "dup.toml"), []byte(`[metadata]
+type = "dup"`), 0644)
+	os.WriteFile(filepath.Join(presetsDir, "tools", "single.toml"), []byte(`[scripts]
+run = "v1"`), 0644)
+
+	registry := config.NewPresetRegistry([]config.PresetLayer{config.DirLayer(presetsDir)})
+	if _, err := registry.Load("dup"); err == nil {
+		t.Error("Expected ambiguity error for preset defined in two subdirectories")
+	}
+
+	first, err := registry.Load("single")
+	if err != nil {
+		t.Fatalf("Load(single) failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(presetsDir, "tools", "single.toml"), []byte(`[scripts]
+run = "v2"`), 0644)
+	second, err := registry.Load("single")
+	if err != nil {
+		t.Fatalf("Load(single) failed: %v", err)
+	}
+	if first != second || second.Scripts["run"].Cmd != "v1" {
+		t.Errorf("Expected cached preset to be reused, got %q", second.Scripts["run"].Cmd)
+	}
+}
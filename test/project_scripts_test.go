@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"mngproj/pkg/secrets"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectScripts(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "api"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "projscripts"
+[project.env]
+GREETING = "hello"
+[project.scripts]
+prebootstrap = "echo pre > log"
+bootstrap = ["echo $GREETING from $(pwd) >> log", "@api:setup", "@seed demo"]
+seed = "echo seed >> log"
+notify = "echo notify >> log"
+loop = "@api:loop"
+deploy = { cmd = "echo project deploy >> log", when = "os == 'plan9'" }
+
+[[components]]
+name = "api"
+type = "lang"
+path = "api"
+[components.scripts]
+setup = "echo setup in $(basename $(pwd)) >> ../log"
+release = "@:notify"
+loop = "@:loop"
+deploy = "echo api deploy >> log"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	readLog := func() string {
+		data, _ := os.ReadFile(filepath.Join(projectDir, "log"))
+		return string(data)
+	}
+
+	if err := mgr.ExecuteScript(manager.ProjectScope, "bootstrap", nil, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+	want := "pre\nhello from " + projectDir + "\nsetup in api\nseed demo\n"
+	if got := readLog(); got != want {
+		t.Errorf("unexpected log\n got %q\nwant %q", got, want)
+	}
+
+	// Components reach project scripts as @:script
+	os.Remove(filepath.Join(projectDir, "log"))
+	if err := mgr.ExecuteScript("api", "release", nil, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if got := readLog(); got != "notify\n" {
+		t.Errorf("unexpected log %q", got)
+	}
+
+	err = mgr.ExecuteScript(manager.ProjectScope, "loop", nil, &bytes.Buffer{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected reference cycle, got %v", err)
+	}
+	err = mgr.ExecuteScript(manager.ProjectScope, "missing", nil, &bytes.Buffer{}, nil)
+	if err == nil || !strings.Contains(err.Error(), `project script "missing" not defined`) {
+		t.Errorf("expected missing project script error, got %v", err)
+	}
+
+	scripts, err := mgr.ProjectScripts()
+	if err != nil {
+		t.Fatalf("ProjectScripts failed: %v", err)
+	}
+	if scripts.ScriptSources["bootstrap"] != "project" || len(scripts.Hooks["prebootstrap"]) != 1 {
+		t.Errorf("unexpected project scripts %+v", scripts)
+	}
+
+	// Project scripts whose `when` does not hold are not there
+	if ok, err := mgr.HasProjectScript("deploy"); ok || err != nil {
+		t.Errorf("expected deploy to be inactive, got %v, %v", ok, err)
+	}
+	if ok, err := mgr.HasProjectScript("seed"); !ok || err != nil {
+		t.Errorf("expected seed to be active, got %v, %v", ok, err)
+	}
+}
+
+func TestProjectScriptSecrets(t *testing.T) {
+	projectDir := t.TempDir()
+	t.Setenv(secrets.KeyEnvVar, "")
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "projsecrets"
+[project.env]
+TOKEN = "secret:token"
+[project.scripts]
+show = "echo token is $TOKEN > out"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	store, err := mgr.OpenSecrets(true)
+	if err != nil {
+		t.Fatalf("OpenSecrets failed: %v", err)
+	}
+	store.Set(manager.ProjectScope, "token", "t0ken")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := mgr.ExecuteScript(manager.ProjectScope, "show", nil, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(projectDir, "out")); string(data) != "token is t0ken\n" {
+		t.Errorf("unexpected output %q", data)
+	}
+
+	// The project scope is reserved when the project declares scripts
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "projsecrets"
+[project.scripts]
+show = "echo project"
+
+[[components]]
+name = "project"
+path = "."
+`), 0644)
+	if _, err := manager.New(projectDir); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected reserved name error, got %v", err)
+	}
+
+	// Otherwise a component may use the name
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "projsecrets"
+
+[[components]]
+name = "project"
+path = "."
+[components.scripts]
+show = "echo component > out"
+`), 0644)
+	mgr, err = manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if err := mgr.ExecuteScript("project", "show", nil, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(projectDir, "out")); string(data) != "component\n" {
+		t.Errorf("unexpected output %q", data)
+	}
+}
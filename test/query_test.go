@@ -0,0 +1,57 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"mngproj/pkg/cmd"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryOutputKeys(t *testing.T) {
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "query"
+
+[[components]]
+name = "api"
+types = ["go"]
+path = "api"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	cmd.HandleQuery(mgr, nil)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	var components []map[string]any
+	if err := json.Unmarshal(out, &components); err != nil {
+		t.Fatalf("query output is not JSON: %v\n%s", err, out)
+	}
+	if len(components) != 1 {
+		t.Fatalf("expected 1 component, got %s", out)
+	}
+	// The keys are the CLI's output format; empty fields are still present
+	for _, key := range []string{"Name", "Type", "Types", "Path", "Priority", "Groups", "Dependencies", "Env", "Scripts", "Params", "EnvFiles", "EffectiveTypes"} {
+		if _, ok := components[0][key]; !ok {
+			t.Errorf("query output lacks %q: %s", key, out)
+		}
+	}
+	if components[0]["Name"] != "api" || components[0]["Type"] != "" {
+		t.Errorf("unexpected query output %s", out)
+	}
+}
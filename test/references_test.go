@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptReferences(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "shared"), 0755)
+	// A command whose name starts with @, like `@echo off` on Windows
+	os.MkdirAll(filepath.Join(projectDir, "bin"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "bin", "@notify"), []byte("#!/bin/sh\necho notified \"$@\"\n"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "lang.toml"), []byte(`
+[metadata]
+type = "lang"
+role = "language"
+[scripts]
+lint = "echo lint"
+test = ["@lint", "echo test"]
+build = "echo build"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "refs"
+
+[[components]]
+name = "app"
+type = "lang"
+[components.scripts]
+prebuild = "@shared:codegen"
+release = ["@lint", "@test", "@build"]
+loop = "@again"
+again = ["echo again", "@loop"]
+ship = "@build"
+announce = ["@notify done", "@lint"]
+[components.env]
+PATH = "${MNGPROJ_ROOT}/bin:${PATH}"
+
+[[components]]
+name = "shared"
+type = "lang"
+path = "shared"
+[components.env]
+WHERE = "shared-env"
+[components.scripts]
+codegen = "echo codegen $WHERE $(basename $(pwd))"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("app", "release", nil, &stdout, nil); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	// lint runs once although test references it again; build's prebuild hook runs codegen in shared
+	want := "lint\ntest\ncodegen shared-env shared\nbuild\n"
+	if stdout.String() != want {
+		t.Errorf("Unexpected release output:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	err = mgr.ExecuteScript("app", "loop", nil, &stdout, nil)
+	if err == nil || !strings.Contains(err.Error(), "cycle: app:loop -> app:again -> app:loop") {
+		t.Errorf("Expected reference cycle error, got %v", err)
+	}
+
+	stdout.Reset()
+	cmd, err := mgr.ExecuteScriptAsync("app", "ship", []string{"--fast"}, &stdout, nil)
+	if err != nil {
+		t.Fatalf("async ship failed: %v", err)
+	}
+	cmd.Wait()
+	if stdout.String() != "codegen shared-env shared\nbuild --fast\n" {
+		t.Errorf("Unexpected async reference output: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := mgr.ExecuteScript("app", "announce", nil, &stdout, nil); err != nil {
+		t.Fatalf("announce failed: %v", err)
+	}
+	// @notify is not a script of app, so it runs as a command
+	if stdout.String() != "notified done\nlint\n" {
+		t.Errorf("Unexpected announce output: %q", stdout.String())
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequiredEnv(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "api"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "web.toml"), []byte(`
+required_env = [{ name = "STRIPE_KEY", description = "from the preset" }]
+
+[metadata]
+type = "web"
+role = "framework"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "required"
+
+[[components]]
+name = "api"
+type = "web"
+path = "api"
+required_env = [
+  "MNGPROJ_TEST_DATABASE_URL",
+  { name = "STRIPE_KEY", pattern = "sk_(test|live)_.+", description = "Stripe secret key" },
+  "REGION",
+]
+[components.env]
+REGION = "eu"
+[components.scripts]
+run = "echo started > started"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	comp, err := mgr.ResolveComponent("api")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	// The component entry replaces the preset's one for the same variable
+	if len(comp.RequiredEnv) != 3 || comp.RequiredEnv[0].Name != "STRIPE_KEY" || comp.RequiredEnv[0].Pattern == "" {
+		t.Fatalf("unexpected required env %+v", comp.RequiredEnv)
+	}
+
+	// Every problem is reported at once and nothing starts
+	mgr.ExtraEnv = map[string]string{"STRIPE_KEY": "pk_live_123"}
+	err = mgr.ExecuteScript("api", "run", nil, &bytes.Buffer{}, nil)
+	if err == nil {
+		t.Fatal("expected missing env error")
+	}
+	for _, want := range []string{
+		"api: MNGPROJ_TEST_DATABASE_URL is not set",
+		`api: STRIPE_KEY does not match "sk_(test|live)_.+" (Stripe secret key)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "REGION") {
+		t.Errorf("REGION is set by the component env: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "api", "started")); err == nil {
+		t.Error("script ran despite missing env")
+	}
+
+	problems, err := mgr.CheckRequiredEnv("run", "api")
+	if err != nil || len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v (%v)", problems, err)
+	}
+
+	// Variables from the process environment count too
+	t.Setenv("MNGPROJ_TEST_DATABASE_URL", "postgres://localhost/app")
+	mgr.ExtraEnv = map[string]string{"STRIPE_KEY": "sk_test_123"}
+	if err := mgr.ExecuteScript("api", "run", nil, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("run failed with all env set: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "api", "started")); err != nil {
+		t.Error("script did not run")
+	}
+}
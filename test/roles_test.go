@@ -0,0 +1,87 @@
+package test
+
+import (
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomRolesAndScriptPins(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "py.toml"), []byte(`
+[metadata]
+type = "py"
+role = "language"
+[scripts]
+run = "python main.py"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "presets", "uv.toml"), []byte(`
+[metadata]
+type = "uv"
+role = "package_manager"
+[scripts]
+run = "uv run main.py"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "presets", "container.toml"), []byte(`
+[metadata]
+type = "container"
+role = "container"
+roles = { container = 25 }
+[scripts]
+run = "docker compose up"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "roles"
+
+[[components]]
+name = "default"
+types = ["py", "uv", "container"]
+
+[[components]]
+name = "pinned"
+types = ["py", "uv", "container"]
+resolution = { scripts = { run = "uv" } }
+
+[[components]]
+name = "prioritised"
+types = ["py", "uv", "container"]
+[components.resolution.role_priority]
+package_manager = 50
+
+[[components]]
+name = "broken"
+types = ["py", "uv"]
+resolution = { scripts = { run = "container" } }
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+
+	for _, tc := range []struct{ comp, cmd, source string }{
+		{"default", "docker compose up", "container"}, // Declared role scores 25, above package_manager
+		{"pinned", "uv run main.py", "uv"},
+		{"prioritised", "uv run main.py", "uv"},
+	} {
+		comp, err := mgr.ResolveComponent(tc.comp)
+		if err != nil {
+			t.Fatalf("ResolveComponent(%s) failed: %v", tc.comp, err)
+		}
+		if got := comp.Scripts["run"].Cmd; got != tc.cmd {
+			t.Errorf("%s: expected run %q, got %q", tc.comp, tc.cmd, got)
+		}
+		if got := comp.ScriptSources["run"]; got != tc.source {
+			t.Errorf("%s: expected source %q, got %q", tc.comp, tc.source, got)
+		}
+	}
+
+	_, err = mgr.ResolveComponent("broken")
+	if err == nil || !strings.Contains(err.Error(), `pinned to preset "container"`) {
+		t.Errorf("expected pin error, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStructuredScripts(t *testing.T) {
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.MkdirAll(filepath.Join(projectDir, "app", "db"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "tool.toml"), []byte(`
+[metadata]
+type = "tool"
+role = "tool"
+[scripts]
+lint = "echo lint"
+fmt = { cmd = "echo fmt", description = "Format sources" }
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "scripts"
+
+[[components]]
+name = "app"
+type = "tool"
+path = "app"
+
+[components.scripts]
+plain = "echo plain"
+flaky = { cmd = "test -f marker || { touch marker; exit 1; }", retries = 1 }
+slow = { cmd = "exec sleep 5", timeout = "100ms" }
+orphan = { cmd = "(sleep 0.5; touch late) & wait", timeout = "100ms" }
+quick = { cmd = "true", timeout = "100ms" }
+
+[components.scripts.migrate]
+cmd = "echo $(basename $(pwd)) $MIGRATE_DIR"
+cwd = "db"
+env = { MIGRATE_DIR = "up" }
+description = "Apply database migrations"
+shell = "sh"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Scripts["fmt"].Description != "Format sources" || comp.ScriptSources["fmt"] != "tool" || comp.ScriptSources["migrate"] != "component" {
+		t.Errorf("Unexpected scripts: %+v sources: %v", comp.Scripts, comp.ScriptSources)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("app", "migrate", nil, &stdout, nil); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if strings.TrimSpace(stdout.String()) != "db up" {
+		t.Errorf("Expected cwd and env of the script, got %q", stdout.String())
+	}
+
+	var stderr bytes.Buffer
+	if err := mgr.ExecuteScript("app", "flaky", nil, &stdout, &stderr); err != nil {
+		t.Errorf("Expected flaky script to pass on retry: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "retrying (1/1)") {
+		t.Errorf("Expected retry notice, got %q", stderr.String())
+	}
+
+	if err := mgr.ExecuteScript("app", "slow", nil, &stdout, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	// A timeout kills the whole process group, including commands the shell started
+	if err := mgr.ExecuteScript("app", "orphan", nil, &stdout, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	proc, err := mgr.ExecuteScriptAsync("app", "slow", nil, &stdout, nil)
+	if err != nil {
+		t.Fatalf("async slow failed to start: %v", err)
+	}
+	if err := proc.Wait(); err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected async timeout error, got %v", err)
+	}
+	proc, err = mgr.ExecuteScriptAsync("app", "quick", nil, &stdout, nil)
+	if err != nil {
+		t.Fatalf("async quick failed to start: %v", err)
+	}
+	if err := proc.Wait(); err != nil {
+		t.Errorf("Expected quick script to pass, got %v", err)
+	}
+	time.Sleep(time.Second)
+	if _, err := os.Stat(filepath.Join(projectDir, "app", "late")); err == nil {
+		t.Error("Child of a timed out script kept running")
+	}
+
+	// Plain scripts are written back as strings in JSON and YAML
+	for _, name := range []string{"out.json", "out.yaml"} {
+		path := filepath.Join(projectDir, name)
+		if err := config.SaveProjectConfig(path, mgr.ProjectConfig); err != nil {
+			t.Fatalf("SaveProjectConfig failed: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), `plain": "echo plain"`) && !strings.Contains(string(data), "plain: echo plain") {
+			t.Errorf("Expected plain script as string in %s:\n%s", name, data)
+		}
+		reloaded, err := config.LoadProjectConfig(path)
+		if err != nil {
+			t.Fatalf("LoadProjectConfig(%s) failed: %v", name, err)
+		}
+		if got := reloaded.Components[0].Scripts["migrate"]; got.Cwd != "db" || got.Env["MIGRATE_DIR"].Value != "up" {
+			t.Errorf("Structured script lost in %s: %+v", name, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/config"
+	"mngproj/pkg/manager"
+	"mngproj/pkg/secrets"
+	"mngproj/pkg/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretsInjectionAndRedaction(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(secrets.KeyEnvVar, "")
+
+	cfg := &config.ProjectConfig{
+		Components: []config.ComponentConfig{
+			{
+				Name:    "api",
+				Path:    ".",
+				Env:     map[string]config.EnvValue{"DB_PASSWORD": {Value: "secret:db_password"}},
+				Scripts: map[string]config.Script{"show": {Cmd: "echo password is {{.Env.DB_PASSWORD}}"}},
+			},
+		},
+	}
+	mgr := &manager.Manager{
+		ProjectConfig: cfg,
+		ProjectDir:    tmpDir,
+		ConfigPath:    filepath.Join(tmpDir, "mngproj.toml"),
+		PresetsDir:    tmpDir,
+	}
+
+	store, err := mgr.OpenSecrets(true)
+	if err != nil {
+		t.Fatalf("OpenSecrets failed: %v", err)
+	}
+	store.Set("api", "db_password", "s3cr3t-value")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(mgr.SecretsPath())
+	if strings.Contains(string(raw), "s3cr3t-value") {
+		t.Fatal("Secrets file contains the plaintext value")
+	}
+	gitignore, _ := os.ReadFile(filepath.Join(tmpDir, ".gitignore"))
+	if !strings.Contains(string(gitignore), secrets.KeyFileName) {
+		t.Error("Generated key file was not added to .gitignore")
+	}
+
+	var out bytes.Buffer
+	pw := &utils.PrefixWriter{Prefix: "api", Writer: &out, Redactor: mgr.Redactor()}
+	if err := mgr.ExecuteScript("api", "show", nil, pw, pw); err != nil {
+		t.Fatalf("ExecuteScript failed: %v", err)
+	}
+	if strings.Contains(out.String(), "s3cr3t-value") {
+		t.Errorf("Secret leaked into multiplexed logs: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "password is ******") {
+		t.Errorf("Expected masked secret in logs, got %q", out.String())
+	}
+
+	cfg.Components[0].Env["MISSING"] = config.EnvValue{Value: "secret:nope"}
+	if err := mgr.ExecuteScript("api", "show", nil, &out, nil); err == nil {
+		t.Error("Expected error for unknown secret reference")
+	}
+}
+
+func TestPrefixWriterBuffersLines(t *testing.T) {
+	redactor := &utils.Redactor{}
+	redactor.Add("s3cr3t-value", "first\nsecond")
+	var out bytes.Buffer
+	pw := &utils.PrefixWriter{Prefix: "api", Writer: &out, Redactor: redactor}
+
+	// Secrets split across writes or lines are still masked
+	for _, chunk := range []string{"token s3c", "r3t-value ok\nkey first", "\nsecond done\npartial"} {
+		pw.Write([]byte(chunk))
+	}
+	if got, want := out.String(), "[api] token ****** ok\n[api] key ****** done\n"; got != want {
+		t.Errorf("unexpected output %q, want %q", got, want)
+	}
+	// The incomplete last line waits for Close instead of getting a newline early
+	pw.Write([]byte(" line"))
+	pw.Close()
+	if got := out.String(); !strings.HasSuffix(got, "done\n[api] partial line\n") {
+		t.Errorf("unexpected output after Close %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStarlarkConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, svc := range []string{"billing", "orders"} {
+		os.MkdirAll(filepath.Join(projectDir, "services", svc), 0755)
+	}
+	os.WriteFile(filepath.Join(projectDir, "ports.json"), []byte(`{"billing": 8001, "orders": 8002}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.star"), []byte(`
+project(name = "starry", discovery = {"paths": []})
+
+ports = json.decode(read_file("ports.json"))
+
+preset("svc",
+    metadata = {"role": "language"},
+    scripts = {"run": "echo $SERVICE on $PORT", "build": ["echo one", "echo two"]},
+)
+
+for d in glob("services/*"):
+    name = d.split("/")[-1]
+    component(
+        name = name,
+        path = d,
+        types = ["svc"],
+        env = {"SERVICE": name, "PORT": str(ports[name])},
+        groups = ["backend"],
+    )
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if mgr.ProjectConfig.Project.Name != "starry" {
+		t.Errorf("project name = %q", mgr.ProjectConfig.Project.Name)
+	}
+	if got := mgr.ListComponentsByGroup("backend"); !slices.Equal(got, []string{"billing", "orders"}) {
+		t.Fatalf("components = %v", got)
+	}
+
+	var stdout bytes.Buffer
+	if err := mgr.ExecuteScript("orders", "run", nil, &stdout, nil); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "orders on 8002" {
+		t.Errorf("unexpected output %q", got)
+	}
+	resolved, err := mgr.ResolveComponent("billing")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if resolved.ScriptSources["build"] != "svc" || len(resolved.Scripts["build"].Steps) != 2 {
+		t.Errorf("inline preset not applied: %+v", resolved.Scripts)
+	}
+
+	// Adding dependencies cannot rewrite a program
+	if err := mgr.AddDependency("billing", "x"); err == nil || !strings.Contains(err.Error(), "Starlark") {
+		t.Errorf("expected AddDependency to refuse mngproj.star, got %v", err)
+	}
+}
+
+func TestStarlarkConfigErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"outside project": `read_file("../secret")`,
+		"unknown field":   `component(name = "a", typo = 1)`,
+		"missing name":    `component(path = ".")`,
+	} {
+		projectDir := t.TempDir()
+		os.WriteFile(filepath.Join(projectDir, "mngproj.star"), []byte(src), 0644)
+		if _, err := manager.New(projectDir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"mngproj/pkg/cmd"
+	"mngproj/pkg/expr"
+	"mngproj/pkg/manager"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWhenExpressions(t *testing.T) {
+	ctx := expr.Context{
+		Vars: map[string]any{"os": "linux", "profile": "dev", "component.types": []string{"go", "docker"}},
+		Env:  func(name string) string { return map[string]string{"CI": "true"}[name] },
+	}
+	cases := map[string]bool{
+		"":                                     true,
+		"os == 'linux'":                        true,
+		`os == "linux" && env.CI != 'true'`:    false,
+		"!(profile == 'prod') || env.CI":       true,
+		"'docker' in component.types":          true,
+		"'rust' in component.types":            false,
+		"env.MISSING == ''":                    true,
+		"profile in 'dev,staging'":             true,
+		"(os != 'linux' || true) && !env.NONE": true,
+	}
+	for src, want := range cases {
+		got, err := expr.Eval(src, ctx)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", src, err)
+		} else if got != want {
+			t.Errorf("Eval(%q) = %v, want %v", src, got, want)
+		}
+	}
+
+	for _, src := range []string{"os ==", "unknown == 'x'", "os == 'linux", "(os == 'linux'", "os = 'linux'"} {
+		if _, err := expr.Eval(src, ctx); err == nil {
+			t.Errorf("Expected error for %q", src)
+		}
+	}
+}
+
+func TestConditionalComponentsAndOverrides(t *testing.T) {
+	t.Setenv("MNGPROJ_PROFILE", "")
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "tool.toml"), []byte(`
+[metadata]
+type = "tool"
+role = "tool"
+[scripts]
+build = "make"
+[[overrides]]
+when = "os == '`+runtime.GOOS+`'"
+scripts = { build = "make native" }
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "when"
+
+[[components]]
+name = "app"
+type = "tool"
+[components.env]
+MODE = "default"
+[[components.overrides]]
+when = "profile == 'prod'"
+env = { MODE = "production" }
+
+[[components.overrides]]
+when = "'tool' in component.types && arch == '`+runtime.GOARCH+`'"
+scripts = { test = "echo native" }
+
+[[components.overrides]]
+when = "os == 'plan9'"
+scripts = { test = "echo plan9" }
+
+[[components]]
+name = "elsewhere"
+type = "tool"
+when = "arch == 'no-such-arch'"
+
+[[components]]
+name = "prod-only"
+type = "tool"
+when = "profile == 'prod'"
+`), 0644)
+
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	if names := strings.Join(mgr.ListComponents(), ","); names != "app" {
+		t.Errorf("Expected only app, got %s", names)
+	}
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Scripts["build"].Cmd != "make native" || comp.Scripts["test"].Cmd != "echo native" || comp.Env["MODE"].Value != "default" {
+		t.Errorf("Unexpected resolution: scripts=%v env=%v", comp.Scripts, comp.Env)
+	}
+
+	if _, err := cmd.ParseEnvFlags(mgr, []string{"--profile", "prod"}); err != nil {
+		t.Fatalf("ParseEnvFlags failed: %v", err)
+	}
+	if names := strings.Join(mgr.ListComponents(), ","); names != "app,prod-only" {
+		t.Errorf("Expected app and prod-only with the prod profile, got %s", names)
+	}
+	comp, err = mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if comp.Env["MODE"].Value != "production" {
+		t.Errorf("Expected production override, got %q", comp.Env["MODE"].Value)
+	}
+
+	// Saving keeps components that are filtered out on this machine
+	if err := mgr.AddDependency("app", "lib"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(projectDir, "mngproj.toml"))
+	if !strings.Contains(string(data), "elsewhere") {
+		t.Errorf("Filtered component was dropped from mngproj.toml:\n%s", data)
+	}
+}
+
+func TestConditionalScriptsAndEnv(t *testing.T) {
+	t.Setenv("MNGPROJ_PROFILE", "")
+	projectDir := t.TempDir()
+	os.MkdirAll(filepath.Join(projectDir, "presets"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "presets", "tool.toml"), []byte(`
+[metadata]
+type = "tool"
+role = "tool"
+[scripts]
+lint = { cmd = "lint", when = "os == 'plan9'" }
+fmt = "fmt"
+`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "when-entries"
+[project.scripts]
+release = { cmd = "release", when = "profile == 'prod'" }
+
+[[components]]
+name = "app"
+type = "tool"
+[components.env]
+NATIVE = { value = "yes", when = "os == '`+runtime.GOOS+`'" }
+PLAN9 = { value = "yes", when = "os == 'plan9'" }
+[components.scripts]
+fmt = { cmd = "fmt --ci", when = "env.CI == 'true'" }
+[components.scripts.deploy]
+cmd = "deploy"
+when = "profile == 'prod'"
+env = { TARGET = { value = "prod", when = "profile == 'prod'" }, DEBUG = { value = "1", when = "profile != 'prod'" } }
+`), 0644)
+
+	t.Setenv("CI", "")
+	mgr, err := manager.New(projectDir)
+	if err != nil {
+		t.Fatalf("Manager New failed: %v", err)
+	}
+	comp, err := mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	if _, ok := comp.Scripts["lint"]; ok {
+		t.Error("preset script with a false condition was kept")
+	}
+	if _, ok := comp.Scripts["deploy"]; ok {
+		t.Error("component script with a false condition was kept")
+	}
+	// A dropped component entry leaves the preset's one in place
+	if comp.Scripts["fmt"].Cmd != "fmt" {
+		t.Errorf("expected the preset fmt, got %q", comp.Scripts["fmt"].Cmd)
+	}
+	if _, ok := comp.Env["PLAN9"]; ok || comp.Env["NATIVE"].Value != "yes" {
+		t.Errorf("unexpected env %v", comp.Env)
+	}
+	project, err := mgr.ProjectScripts()
+	if err != nil {
+		t.Fatalf("ProjectScripts failed: %v", err)
+	}
+	if _, ok := project.Scripts["release"]; ok {
+		t.Error("project script with a false condition was kept")
+	}
+
+	if err := mgr.SetProfile("prod"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	comp, err = mgr.ResolveComponent("app")
+	if err != nil {
+		t.Fatalf("ResolveComponent failed: %v", err)
+	}
+	deploy, ok := comp.Scripts["deploy"]
+	if !ok || deploy.Env["TARGET"].Value != "prod" || len(deploy.Env) != 1 {
+		t.Errorf("unexpected deploy script %+v", deploy)
+	}
+
+	// Misspelled keys are rejected instead of being ignored
+	os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "when-entries"
+
+[[components]]
+name = "app"
+type = "tool"
+[components.scripts]
+fmt = { cmd = "fmt", whne = "os == 'linux'" }
+`), 0644)
+	if _, err := manager.New(projectDir); err == nil || !strings.Contains(err.Error(), "whne") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+
+	// Standard tables are checked too
+	for _, entry := range []struct{ table, field string }{
+		{"[components.scripts.migrate]\ncmd = \"migrate\"\nwhne = \"os == 'linux'\"", "whne"},
+		{"[components.env.FOO]\nvalu = \"x\"", "valu"},
+	} {
+		os.WriteFile(filepath.Join(projectDir, "mngproj.toml"), []byte(`
+[project]
+name = "when-entries"
+
+[[components]]
+name = "app"
+type = "tool"
+`+entry.table+"\n"), 0644)
+		_, err := manager.New(projectDir)
+		if err == nil || !strings.Contains(err.Error(), `unknown field "`+entry.field+`"`) {
+			t.Errorf("expected unknown field %q error, got %v", entry.field, err)
+		}
+	}
+}